@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// changelogCmd represents the changelog command
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Generate a changelog section or release notes from the PRs merged in a range",
+	Long: `Generate a changelog section or GitHub release body from the PRs merged between two revisions.
+
+PRs are found through their merge or squash commits and grouped by conventional-commit type.
+The output is rendered with changelog_template.md or release_template.md in ~/.config/prgen/.
+
+Examples:
+  prgen changelog --from v1.2.0
+  prgen changelog --from v1.2.0 --to v1.3.0 --write CHANGELOG.md
+  prgen changelog --from v1.2.0 --to v1.3.0 --release --publish`,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		version, _ := cmd.Flags().GetString("version")
+		release, _ := cmd.Flags().GetBool("release")
+		writePath, _ := cmd.Flags().GetString("write")
+		publish, _ := cmd.Flags().GetBool("publish")
+
+		if publish && (!release || to == "HEAD") {
+			fmt.Println("Error: --publish requires --release and a tag in --to")
+			os.Exit(1)
+		}
+
+		config, err := internal.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		output, err := internal.GenerateChangelog(config, internal.ChangelogOptions{
+			From:    from,
+			To:      to,
+			Version: version,
+			Release: release,
+		})
+		if err != nil {
+			fmt.Printf("Error generating changelog: %v\n", err)
+			os.Exit(1)
+		}
+
+		switch {
+		case publish:
+			releaseURL, err := internal.PublishRelease(config, to, output)
+			if err != nil {
+				fmt.Printf("Error publishing release: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Published release: %s\n", releaseURL)
+		case writePath != "":
+			if err := internal.PrependChangelog(writePath, output); err != nil {
+				fmt.Printf("Error writing changelog: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Updated %s\n", writePath)
+		default:
+			fmt.Print(output)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+
+	changelogCmd.Flags().String("from", "", "Start of the range, e.g. the previous release tag (required)")
+	changelogCmd.Flags().String("to", "HEAD", "End of the range")
+	changelogCmd.Flags().String("version", "", "Version used as the section heading (default: --to, or Unreleased for HEAD)")
+	changelogCmd.Flags().Bool("release", false, "Render the GitHub release template instead of the changelog template")
+	changelogCmd.Flags().String("write", "", "Prepend the section to this changelog file instead of printing it")
+	changelogCmd.Flags().Bool("publish", false, "Create a GitHub release for the --to tag with the rendered notes")
+	changelogCmd.MarkFlagRequired("from")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// commitCmd represents the commit command
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Generate a commit message for the staged changes",
+	Long: `Generate a commit message for the staged changes (git diff --cached) and commit them.
+
+The message follows commit_instructions.md and commit_example.md in ~/.config/prgen/.
+
+It can also be used as a prepare-commit-msg hook:
+  prgen commit --hook "$1" "$2" "$3"`,
+	Args: cobra.MaximumNArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		hookFlag, _ := cmd.Flags().GetBool("hook")
+		if !hookFlag {
+			internal.GenerateCommit()
+			return
+		}
+
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "prgen: --hook requires the commit message file argument")
+			os.Exit(1)
+		}
+		var source string
+		if len(args) > 1 {
+			source = args[1]
+		}
+
+		// Never block the commit because generation failed
+		if err := internal.RunCommitHook(args[0], source); err != nil {
+			fmt.Fprintf(os.Stderr, "prgen: could not generate commit message: %v\n", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(commitCmd)
+
+	commitCmd.Flags().Bool("hook", false, "Run as a prepare-commit-msg hook (args: <msg-file> [source] [sha])")
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// filesCmd represents the files command
+var filesCmd = &cobra.Command{
+	Use:   "files",
+	Short: "List the changed files in the order they are sent to the LLM",
+	Long: `List the files changed on the current branch ranked by relevance score, with
+the class, churn, path weights and references that make up each score. Files
+at the top are the last to be truncated when the diff is too large.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := internal.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		scores, err := internal.ExplainFilePriorities(config)
+		if err != nil {
+			fmt.Printf("Error scoring files: %v\n", err)
+			os.Exit(1)
+		}
+		if len(scores) == 0 {
+			fmt.Println("No changes found")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RANK\tSCORE\tCLASS\tFILE\tREASONS")
+		for i, score := range scores {
+			fmt.Fprintf(w, "%d\t%.1f\t%s\t%s\t%s\n", i+1, score.Score, score.Class, score.File.Path, score.Explain())
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(filesCmd)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List saved PR generation sessions",
+	Long: `List the PR generation sessions saved in ~/.config/prgen/history/, newest first.
+
+Every generation and refinement is stored with its diff hash, background and feedback.
+Use "prgen history show <id>" to print a session's latest title and body,
+or "prgen resume <id>" to continue it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		sessions := loadSessions()
+		if len(sessions) == 0 {
+			fmt.Println("No saved sessions")
+			return
+		}
+		if limit > 0 && len(sessions) > limit {
+			sessions = sessions[:limit]
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tDATE\tREPO\tBRANCH\tSTATUS\tTITLE")
+		for _, session := range sessions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				session.ID,
+				session.UpdatedAt.Format("2006-01-02 15:04"),
+				filepath.Base(session.RepoRoot),
+				session.Branch,
+				session.Status,
+				session.Current().Title,
+			)
+		}
+		w.Flush()
+	},
+}
+
+// historyShowCmd represents the history show command
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Print the latest title and body of a saved session",
+	Long: `Print the latest title and body of a saved session, e.g. to reuse it elsewhere.
+With --all, every iteration is printed together with the feedback that produced it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+
+		config, err := internal.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		session, err := internal.NewHistoryStore(config).Load(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if !all {
+			current := session.Current()
+			fmt.Printf("%s\n\n%s\n", current.Title, current.Body)
+			return
+		}
+
+		for i, iteration := range session.Iterations {
+			fmt.Printf("=== Iteration %d (%s) ===\n", i+1, iteration.CreatedAt.Format("2006-01-02 15:04"))
+			if iteration.Feedback != "" {
+				fmt.Printf("Feedback: %s\n", iteration.Feedback)
+			}
+			fmt.Printf("\n%s\n\n%s\n\n", iteration.Title, iteration.Body)
+		}
+	},
+}
+
+// loadSessions returns all saved sessions, exiting on error
+func loadSessions() []*internal.Session {
+	config, err := internal.LoadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	sessions, err := internal.NewHistoryStore(config).List()
+	if err != nil {
+		fmt.Printf("Error reading history: %v\n", err)
+		os.Exit(1)
+	}
+	return sessions
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyShowCmd)
+
+	historyCmd.Flags().IntP("limit", "n", 20, "Maximum number of sessions to list (0 for all)")
+	historyShowCmd.Flags().Bool("all", false, "Print every iteration instead of only the latest")
+}
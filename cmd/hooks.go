@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// hooksCmd represents the hooks command
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Install or uninstall git hooks that run prgen automatically",
+}
+
+// hooksInstallCmd represents the hooks install command
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the prepare-commit-msg hook (and optionally pre-push) into this repository",
+	Long: `Install git hooks into the repository's hooks directory (respecting core.hooksPath).
+
+prepare-commit-msg fills in a generated commit message.
+pre-push (with --pre-push) creates a PR for the branch in non-interactive mode if there is none yet.
+
+Existing hooks are kept and called before prgen.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		prePush, _ := cmd.Flags().GetBool("pre-push")
+
+		results, err := internal.InstallHooks(prePush)
+		for _, result := range results {
+			if result.Chained {
+				fmt.Printf("Installed %s (existing hook is chained): %s\n", result.Name, result.Path)
+			} else {
+				fmt.Printf("Installed %s: %s\n", result.Name, result.Path)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Error installing hooks: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// hooksUninstallCmd represents the hooks uninstall command
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove prgen's hooks from this repository and restore chained hooks",
+	Run: func(cmd *cobra.Command, args []string) {
+		results, err := internal.UninstallHooks()
+		for _, result := range results {
+			switch {
+			case result.Skipped:
+				fmt.Printf("Skipped %s: not installed by prgen\n", result.Name)
+			case result.Chained:
+				fmt.Printf("Removed %s and restored the previous hook: %s\n", result.Name, result.Path)
+			default:
+				fmt.Printf("Removed %s: %s\n", result.Name, result.Path)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Error uninstalling hooks: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksUninstallCmd)
+
+	hooksInstallCmd.Flags().Bool("pre-push", false, "Also install a pre-push hook that creates the PR non-interactively")
+}
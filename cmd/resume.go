@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// resumeCmd represents the resume command
+var resumeCmd = &cobra.Command{
+	Use:   "resume [id]",
+	Short: "Continue the last unfinished PR generation session",
+	Long: `Continue a saved PR generation session from its latest title and body,
+for example after pushing or creating the PR failed.
+
+Without an ID, the latest session for the current branch without a PR is resumed.
+Use "prgen history" to find the ID of an older session.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var id string
+		if len(args) > 0 {
+			id = args[0]
+		}
+		prState, _ := cmd.Flags().GetString("state")
+		mergeMethod, _ := cmd.Flags().GetString("merge-method")
+		titles, _ := cmd.Flags().GetInt("titles")
+		internal.ResumeSession(id, internal.RunOptions{
+			PRState:     prState,
+			MergeMethod: mergeMethod,
+			Titles:      titles,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+
+	resumeCmd.Flags().StringP("state", "s", "", "PR state to create: draft, ready or auto-merge (overrides config)")
+	resumeCmd.Flags().String("merge-method", "", "Merge method for auto-merge: merge, squash or rebase (overrides config)")
+	resumeCmd.Flags().Int("titles", 0, "Number of alternative titles offered when refining (overrides config)")
+}
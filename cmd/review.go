@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// reviewCmd represents the review command
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Review the current branch's changes with AI before humans do",
+	Long: `Send the filtered diff of the current branch to the LLM with the review instructions
+in ~/.config/prgen/review_instructions.md and show the findings by file, line and severity.
+
+With --post, the findings are posted as review comments on the branch's PR.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		post, _ := cmd.Flags().GetBool("post")
+		internal.RunReview(post)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+
+	reviewCmd.Flags().Bool("post", false, "Post the findings as review comments on the current branch's PR")
+}
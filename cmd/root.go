@@ -6,7 +6,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 
 	"github.com/lugen4ro/prgen/internal"
 	"github.com/spf13/cobra"
@@ -21,6 +20,10 @@ prgen is a CLI tool that allows users to generate and create PRs on GitHub in se
 Customize your experience to get exactly the PR you want.
 
 Your personal config files such as templates are stored under ~/.config/prgen/`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		internal.SetResponseCacheEnabled(!noCache)
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	Run: func(cmd *cobra.Command, args []string) {
@@ -29,7 +32,24 @@ Your personal config files such as templates are stored under ~/.config/prgen/`,
 			openConfigFile()
 			return
 		}
-		internal.Construct()
+		prState, _ := cmd.Flags().GetString("state")
+		mergeMethod, _ := cmd.Flags().GetString("merge-method")
+		nonInteractive, _ := cmd.Flags().GetBool("yes")
+		review, _ := cmd.Flags().GetBool("review")
+		titles, _ := cmd.Flags().GetInt("titles")
+		variants, _ := cmd.Flags().GetBool("variants")
+		skipTests, _ := cmd.Flags().GetBool("skip-tests")
+		bench, _ := cmd.Flags().GetBool("bench")
+		internal.Construct(internal.RunOptions{
+			PRState:        prState,
+			MergeMethod:    mergeMethod,
+			NonInteractive: nonInteractive,
+			Review:         review,
+			Titles:         titles,
+			BodyVariants:   variants,
+			SkipTests:      skipTests,
+			Bench:          bench,
+		})
 	},
 }
 
@@ -48,11 +68,20 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.prgen.yaml)")
+	rootCmd.PersistentFlags().Bool("no-cache", false, "Always call the LLM instead of reusing cached responses")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	rootCmd.Flags().BoolP("config", "c", false, "Open the main config file in the default editor")
+	rootCmd.Flags().StringP("state", "s", "", "PR state to create: draft, ready or auto-merge (overrides config)")
+	rootCmd.Flags().String("merge-method", "", "Merge method for auto-merge: merge, squash or rebase (overrides config)")
+	rootCmd.Flags().BoolP("yes", "y", false, "Non-interactive mode: accept the generated PR without prompting")
+	rootCmd.Flags().Bool("review", false, "Add an AI self-review of the diff as a Reviewer Notes section")
+	rootCmd.Flags().Int("titles", 0, "Number of alternative titles offered when refining (overrides config)")
+	rootCmd.Flags().Bool("variants", false, "Generate concise, standard and detailed variants and pick one")
+	rootCmd.Flags().Bool("skip-tests", false, "Do not run the configured test command")
+	rootCmd.Flags().Bool("bench", false, "Compare benchmarks with the base branch even if the change is not labelled perf")
 }
 
 // openConfigFile opens the main config file with the default editor
@@ -65,24 +94,9 @@ func openConfigFile() {
 
 	configPath := config.GetConfigPath()
 
-	// Try to get the default editor from environment variables
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		editor = os.Getenv("VISUAL")
-	}
-	if editor == "" {
-		// Default to common editors based on OS
-		editor = "vi" // Unix default
-	}
-
-	cmd := exec.Command(editor, configPath)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	err = cmd.Run()
+	err = internal.OpenFileInEditor(configPath)
 	if err != nil {
-		fmt.Printf("Error opening config file with %s: %v\n", editor, err)
+		fmt.Printf("Error opening config file: %v\n", err)
 		fmt.Printf("Config file location: %s\n", configPath)
 		os.Exit(1)
 	}
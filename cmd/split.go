@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// splitCmd represents the split command
+var splitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Suggest splitting the current branch into smaller stacked PRs",
+	Long: `Group the changes on the current branch into related parts by scope, directory
+and which files are changed together, and propose them as smaller stacked PRs
+with generated titles.
+
+With --create, the parts are created as branches based on each other, pushed,
+and opened as draft PRs after confirmation. The working tree is not touched and
+the last branch has the same content as the current one.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		create, _ := cmd.Flags().GetBool("create")
+		internal.SuggestSplit(internal.SplitOptions{Create: create})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+
+	splitCmd.Flags().Bool("create", false, "Create the stacked branches and draft PRs after confirmation")
+}
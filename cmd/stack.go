@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// stackCmd represents the stack command
+var stackCmd = &cobra.Command{
+	Use:   "stack",
+	Short: "Show the stack of branches the current branch belongs to",
+	Long: `Show the branches stacked below and above the current branch with their PRs.
+
+A branch's parent comes from the stack_parents config map, a "Stack-Parent:"
+commit trailer, or the local branch it was most recently branched from.
+With --update, the stack section of every PR in the stack is rewritten, e.g.
+after a branch was added to or removed from the stack.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		update, _ := cmd.Flags().GetBool("update")
+		internal.ShowCurrentStack(update)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stackCmd)
+
+	stackCmd.Flags().Bool("update", false, "Rewrite the stack section of every PR in the stack")
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lugen4ro/prgen/internal"
+	"github.com/spf13/cobra"
+)
+
+// usageCmd represents the usage command
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report LLM token usage and cost per day and per repository",
+	Long: `Report the token usage and cost of every LLM call prgen made, from the log in
+~/.config/prgen/usage.jsonl, with totals per day and per repository.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		days, _ := cmd.Flags().GetInt("days")
+
+		config, err := internal.LoadConfig()
+		if err != nil {
+			fmt.Printf("Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+
+		var since time.Time
+		if days > 0 {
+			year, month, day := time.Now().AddDate(0, 0, -(days - 1)).Date()
+			since = time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+		}
+		records, err := internal.LoadUsageLog(config, since)
+		if err != nil {
+			fmt.Printf("Error reading usage log: %v\n", err)
+			os.Exit(1)
+		}
+		if len(records) == 0 {
+			fmt.Println("No usage recorded")
+			return
+		}
+
+		printUsageTable("DAY", internal.SummarizeUsage(records, internal.UsageDay))
+		fmt.Println()
+		repos := internal.SummarizeUsage(records, internal.UsageRepo)
+		internal.LabelUsageRepos(repos)
+		printUsageTable("REPO", repos)
+
+		var total internal.UsageTotals
+		for _, record := range records {
+			total.Add(record)
+		}
+		fmt.Printf("\nTotal: %d call(s), %d input / %d output tokens, $%.4f\n",
+			total.Calls, total.InputTokens, total.OutputTokens, total.CostUSD)
+	},
+}
+
+// printUsageTable prints usage totals grouped under the given heading
+func printUsageTable(heading string, groups []internal.UsageGroup) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tCALLS\tINPUT\tOUTPUT\tCOST (USD)\n", heading)
+	for _, group := range groups {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.4f\n",
+			group.Label, group.Totals.Calls, group.Totals.InputTokens, group.Totals.OutputTokens, group.Totals.CostUSD)
+	}
+	w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+
+	usageCmd.Flags().IntP("days", "d", 30, "Number of days to report, including today (0 for all)")
+}
@@ -0,0 +1,494 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultBenchTimeout is how long the benchmark command may run per revision when bench_timeout_minutes is not configured
+const DefaultBenchTimeout = 30 * time.Minute
+
+// benchSignificance is the p-value below which a difference is reported, as in benchstat
+const benchSignificance = 0.05
+
+// minBenchSamples is the number of runs per revision needed before a difference can be significant
+const minBenchSamples = 4
+
+// maxExactMannWhitney bounds the product of the sample sizes for which the exact U distribution is computed
+const maxExactMannWhitney = 400
+
+// benchOutputTailLines is how much output is kept when the benchmark command fails
+const benchOutputTailLines = 20
+
+const benchSectionHeading = "## Benchmarks"
+
+// benchmarkKey identifies a benchmark across runs
+type benchmarkKey struct {
+	Package string
+	Name    string
+}
+
+// BenchmarkRun holds the samples of one run of the benchmark command
+type BenchmarkRun struct {
+	keys    []benchmarkKey // In order of first appearance
+	units   []string       // In order of first appearance
+	samples map[benchmarkKey]map[string][]float64
+}
+
+// BenchmarkSummary describes the samples of a benchmark in one unit
+type BenchmarkSummary struct {
+	Center float64 `json:"center"` // Median
+	Spread float64 `json:"spread"` // Largest deviation from the median, in percent of it
+	N      int     `json:"n"`
+}
+
+// BenchmarkComparison compares a benchmark in one unit between the base and head revisions
+type BenchmarkComparison struct {
+	Name  string           `json:"name"`
+	Unit  string           `json:"unit"`
+	Base  BenchmarkSummary `json:"base"`  // N is 0 for benchmarks added on the branch
+	Head  BenchmarkSummary `json:"head"`  // N is 0 for benchmarks removed on the branch
+	Delta float64          `json:"delta"` // Percent change of the head center from the base center
+	P     float64          `json:"p"`
+}
+
+// BenchmarkReport is the comparison attached to the PR body
+type BenchmarkReport struct {
+	Command        string                `json:"command"`
+	Comparisons    []BenchmarkComparison `json:"comparisons"`
+	Table          string                `json:"table"`
+	Interpretation string                `json:"interpretation,omitempty"`
+}
+
+// Significant reports whether the difference is unlikely to be noise
+func (c BenchmarkComparison) Significant() bool {
+	return c.Base.N > 0 && c.Head.N > 0 && c.P < benchSignificance
+}
+
+// RunBenchmarks runs the configured bench_command on rev in a temporary worktree and parses its output.
+// Unlike test results, base runs are not cached: numbers are only comparable when measured together.
+func RunBenchmarks(config *Config, rev string) (*BenchmarkRun, error) {
+	command := config.GetString("bench_command", "")
+	if command == "" {
+		return nil, fmt.Errorf("no bench_command configured")
+	}
+
+	dir, cleanup, err := AddTemporaryWorktree(rev)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	timeout := DefaultBenchTimeout
+	if minutes := config.GetInt("bench_timeout_minutes", 0); minutes > 0 {
+		timeout = time.Duration(minutes) * time.Minute
+	}
+	output, err := runShellCommand(dir, command, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("benchmark command failed: %w\n%s", err, lastLines(string(output), benchOutputTailLines))
+	}
+
+	run := parseBenchmarkOutput(string(output))
+	if len(run.keys) == 0 {
+		return nil, fmt.Errorf("no benchmark results in the output of %q", command)
+	}
+	return run, nil
+}
+
+var (
+	benchmarkLinePattern = regexp.MustCompile(`^(Benchmark\S+)\s+\d+\s+(.+)$`)
+	benchmarkProcsSuffix = regexp.MustCompile(`-\d+$`)
+)
+
+// parseBenchmarkOutput reads Go benchmark format lines, e.g.
+// "BenchmarkParse-8  100000  1203 ns/op  512 B/op  7 allocs/op", repeated once per -count
+func parseBenchmarkOutput(output string) *BenchmarkRun {
+	run := &BenchmarkRun{samples: make(map[benchmarkKey]map[string][]float64)}
+	seenUnits := make(map[string]bool)
+	pkg := ""
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimSpace(strings.TrimPrefix(line, "pkg: "))
+			continue
+		}
+		match := benchmarkLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		// Values come in pairs of number and unit
+		fields := strings.Fields(match[2])
+		if len(fields)%2 != 0 {
+			continue
+		}
+		name := strings.TrimPrefix(benchmarkProcsSuffix.ReplaceAllString(match[1], ""), "Benchmark")
+		key := benchmarkKey{Package: pkg, Name: name}
+		for i := 0; i < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			unit := fields[i+1]
+			if run.samples[key] == nil {
+				run.samples[key] = make(map[string][]float64)
+				run.keys = append(run.keys, key)
+			}
+			if !seenUnits[unit] {
+				seenUnits[unit] = true
+				run.units = append(run.units, unit)
+			}
+			run.samples[key][unit] = append(run.samples[key][unit], value)
+		}
+	}
+	return run
+}
+
+// CompareBenchmarks compares every benchmark in every unit, grouped by unit in the order they were reported
+func CompareBenchmarks(base, head *BenchmarkRun) []BenchmarkComparison {
+	// Benchmarks removed on the branch are listed after the ones that still exist
+	keys := append([]benchmarkKey{}, head.keys...)
+	for _, key := range base.keys {
+		if head.samples[key] == nil {
+			keys = append(keys, key)
+		}
+	}
+	units := append([]string{}, head.units...)
+	for _, unit := range base.units {
+		if !containsString(units, unit) {
+			units = append(units, unit)
+		}
+	}
+
+	// Names are qualified with the package when benchmarks come from several packages
+	packages := make(map[string]bool)
+	for _, key := range keys {
+		packages[key.Package] = true
+	}
+
+	var comparisons []BenchmarkComparison
+	for _, unit := range units {
+		for _, key := range keys {
+			before, after := base.samples[key][unit], head.samples[key][unit]
+			if len(before) == 0 && len(after) == 0 {
+				continue
+			}
+			name := key.Name
+			if len(packages) > 1 && key.Package != "" {
+				name = path.Base(key.Package) + "." + name
+			}
+
+			comparison := BenchmarkComparison{
+				Name: name,
+				Unit: unit,
+				Base: summarizeSamples(before),
+				Head: summarizeSamples(after),
+				P:    mannWhitneyP(before, after),
+			}
+			if comparison.Base.Center != 0 {
+				comparison.Delta = (comparison.Head.Center - comparison.Base.Center) / comparison.Base.Center * 100
+			}
+			comparisons = append(comparisons, comparison)
+		}
+	}
+	return comparisons
+}
+
+// summarizeSamples returns the median of the samples and their largest deviation from it
+func summarizeSamples(values []float64) BenchmarkSummary {
+	if len(values) == 0 {
+		return BenchmarkSummary{}
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	spread := 0.0
+	if median != 0 {
+		spread = math.Max(median-sorted[0], sorted[len(sorted)-1]-median) / math.Abs(median) * 100
+	}
+	return BenchmarkSummary{Center: median, Spread: spread, N: len(values)}
+}
+
+// mannWhitneyP returns the two-sided p-value of the Mann-Whitney U test that x and y come from
+// the same distribution. Small samples without ties use the exact distribution of U, others the
+// normal approximation with tie correction.
+func mannWhitneyP(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type sample struct {
+		value float64
+		fromX bool
+	}
+	all := make([]sample, 0, n1+n2)
+	for _, value := range x {
+		all = append(all, sample{value, true})
+	}
+	for _, value := range y {
+		all = append(all, sample{value, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Ties share the average of their ranks
+	rankSum, ties := 0.0, 0.0
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+1+j) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromX {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	u := rankSum - float64(n1*(n1+1))/2
+
+	if ties == 0 && n1*n2 <= maxExactMannWhitney {
+		return mannWhitneyExactP(int(u), n1, n2)
+	}
+
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * ((n + 1) - ties/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+	z := math.Max(math.Abs(u-mean)-0.5, 0) / math.Sqrt(variance)
+	return math.Erfc(z / math.Sqrt2)
+}
+
+// mannWhitneyExactP returns the two-sided p-value of u from the exact distribution of U without ties
+func mannWhitneyExactP(u, n1, n2 int) float64 {
+	// counts[i][j][k] is the number of orderings of i x values and j y values where U = k.
+	// The largest value is either an x, which beats all j y values, or a y, which beats none.
+	counts := make([][][]float64, n1+1)
+	for i := range counts {
+		counts[i] = make([][]float64, n2+1)
+		for j := range counts[i] {
+			counts[i][j] = make([]float64, i*j+1)
+			if i == 0 || j == 0 {
+				counts[i][j][0] = 1
+				continue
+			}
+			for k := range counts[i][j] {
+				if k >= j && k-j < len(counts[i-1][j]) {
+					counts[i][j][k] += counts[i-1][j][k-j]
+				}
+				if k < len(counts[i][j-1]) {
+					counts[i][j][k] += counts[i][j-1][k]
+				}
+			}
+		}
+	}
+
+	distribution := counts[n1][n2]
+	total, below, above := 0.0, 0.0, 0.0
+	for k, count := range distribution {
+		total += count
+		if k <= u {
+			below += count
+		}
+		if k >= u {
+			above += count
+		}
+	}
+	return math.Min(1, 2*math.Min(below, above)/total)
+}
+
+// FormatBenchmarkTable renders the comparisons in the style of benchstat, one table per unit
+func FormatBenchmarkTable(comparisons []BenchmarkComparison) string {
+	var result strings.Builder
+	for i := 0; i < len(comparisons); {
+		unit := comparisons[i].Unit
+		j := i
+		for j < len(comparisons) && comparisons[j].Unit == unit {
+			j++
+		}
+		if i > 0 {
+			result.WriteString("\n")
+		}
+		writeBenchmarkTable(&result, unit, comparisons[i:j])
+		i = j
+	}
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// writeBenchmarkTable writes the table of one unit, with a geometric mean row when there are several benchmarks
+func writeBenchmarkTable(result *strings.Builder, unit string, comparisons []BenchmarkComparison) {
+	writer := tabwriter.NewWriter(result, 0, 0, 2, ' ', 0)
+	title := benchUnitTitle(unit)
+	fmt.Fprintf(writer, "name\told %s\tnew %s\tdelta\n", title, title)
+
+	logBase, logHead, compared := 0.0, 0.0, 0
+	for _, c := range comparisons {
+		scale := math.Max(c.Base.Center, c.Head.Center)
+		before, after := "-", "-"
+		if c.Base.N > 0 {
+			before = fmt.Sprintf("%s ± %.0f%%", formatBenchValue(c.Base.Center, unit, scale), c.Base.Spread)
+		}
+		if c.Head.N > 0 {
+			after = fmt.Sprintf("%s ± %.0f%%", formatBenchValue(c.Head.Center, unit, scale), c.Head.Spread)
+		}
+
+		var delta string
+		switch {
+		case c.Base.N == 0:
+			delta = "(new)"
+		case c.Head.N == 0:
+			delta = "(removed)"
+		case c.Base.Center == c.Head.Center && c.Base.Spread == 0 && c.Head.Spread == 0:
+			delta = "~  (all equal)"
+		case c.Significant():
+			delta = fmt.Sprintf("%+.2f%%  (p=%.3f n=%d+%d)", c.Delta, c.P, c.Base.N, c.Head.N)
+		default:
+			delta = fmt.Sprintf("~  (p=%.3f n=%d+%d)", c.P, c.Base.N, c.Head.N)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", c.Name, before, after, delta)
+
+		if c.Base.N > 0 && c.Head.N > 0 && c.Base.Center > 0 && c.Head.Center > 0 {
+			logBase += math.Log(c.Base.Center)
+			logHead += math.Log(c.Head.Center)
+			compared++
+		}
+	}
+
+	if compared > 1 {
+		geoBase := math.Exp(logBase / float64(compared))
+		geoHead := math.Exp(logHead / float64(compared))
+		scale := math.Max(geoBase, geoHead)
+		fmt.Fprintf(writer, "[Geo mean]\t%s\t%s\t%+.2f%%\n",
+			formatBenchValue(geoBase, unit, scale), formatBenchValue(geoHead, unit, scale), (geoHead-geoBase)/geoBase*100)
+	}
+	writer.Flush()
+}
+
+// benchUnitTitle returns the column title of a unit, using benchstat's names for the standard ones
+func benchUnitTitle(unit string) string {
+	switch unit {
+	case "ns/op":
+		return "time/op"
+	case "B/op":
+		return "alloc/op"
+	case "MB/s":
+		return "speed"
+	}
+	return unit
+}
+
+// benchScale is a unit prefix for values at or above factor
+type benchScale struct {
+	factor float64
+	suffix string
+}
+
+// Scales of the standard units, largest first
+var (
+	timeScales = []benchScale{{1e9, "s"}, {1e6, "ms"}, {1e3, "µs"}, {1, "ns"}}
+	byteScales = []benchScale{{1 << 30, "GiB"}, {1 << 20, "MiB"}, {1 << 10, "KiB"}, {1, "B"}}
+)
+
+// formatBenchValue formats a value with a readable scale chosen from ref, so values in a row share it
+func formatBenchValue(value float64, unit string, ref float64) string {
+	var scales []benchScale
+	switch unit {
+	case "ns/op":
+		scales = timeScales
+	case "B/op":
+		scales = byteScales
+	case "MB/s":
+		return formatSignificant(value) + "MB/s"
+	default:
+		return formatSignificant(value)
+	}
+	for _, scale := range scales[:len(scales)-1] {
+		if ref >= scale.factor {
+			return formatSignificant(value/scale.factor) + scale.suffix
+		}
+	}
+	return formatSignificant(value) + scales[len(scales)-1].suffix
+}
+
+// formatSignificant formats a value with about three significant digits
+func formatSignificant(value float64) string {
+	switch magnitude := math.Abs(value); {
+	case magnitude == 0:
+		return "0"
+	case magnitude >= 100:
+		return fmt.Sprintf("%.0f", value)
+	case magnitude >= 10:
+		return fmt.Sprintf("%.1f", value)
+	}
+	return fmt.Sprintf("%.2f", value)
+}
+
+// HasFewBenchmarkSamples reports whether some benchmark ran too few times for a difference to be significant
+func HasFewBenchmarkSamples(comparisons []BenchmarkComparison) bool {
+	for _, c := range comparisons {
+		if (c.Base.N > 0 && c.Base.N < minBenchSamples) || (c.Head.N > 0 && c.Head.N < minBenchSamples) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPerformanceChange reports whether the branch is labelled as a performance change,
+// by a "perf/" or "perf-" branch name or a conventional-commit "perf" commit since base
+func IsPerformanceChange(branch, base string) bool {
+	if strings.HasPrefix(branch, "perf/") || strings.HasPrefix(branch, "perf-") {
+		return true
+	}
+	commits, err := GetFirstParentCommits(base, "HEAD")
+	if err != nil {
+		return false
+	}
+	for _, commit := range commits {
+		if match := conventionalPattern.FindStringSubmatch(commit.Subject); match != nil && strings.EqualFold(match[1], "perf") {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildBenchmarkPromptSection tells the LLM about the benchmark results that are attached to the body
+func BuildBenchmarkPromptSection(report *BenchmarkReport) string {
+	var result strings.Builder
+	result.WriteString("Benchmarks were run on the base and head revisions. The table below and an interpretation are appended to the PR body automatically, so do not repeat the table. ")
+	result.WriteString("Only claim performance changes that it shows; \"~\" means no significant difference.\n")
+	result.WriteString(report.Table)
+	return result.String()
+}
+
+// AppendBenchmarkSection adds the benchmark table and its interpretation to the PR body
+func AppendBenchmarkSection(body string, report *BenchmarkReport) string {
+	if report == nil || report.Table == "" || strings.Contains(body, benchSectionHeading) {
+		return body
+	}
+
+	var section strings.Builder
+	section.WriteString(benchSectionHeading + "\n\n")
+	if report.Interpretation != "" {
+		section.WriteString(report.Interpretation + "\n\n")
+	}
+	section.WriteString(fmt.Sprintf("Base and head compared with `%s`:\n\n", report.Command))
+	section.WriteString("```\n" + report.Table + "\n```\n")
+
+	return strings.TrimRight(body, "\n") + "\n\n" + strings.TrimRight(section.String(), "\n")
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultTitleCandidates is how many alternative titles are suggested when title_candidates is not configured
+const DefaultTitleCandidates = 3
+
+// DefaultBodyVariants are the verbosity levels generated with --variants when body_variants is not configured
+var DefaultBodyVariants = []string{"concise", "standard", "detailed"}
+
+// bodyVerbosityGuidance describes each known verbosity level to the model.
+// Other levels are passed through as written.
+var bodyVerbosityGuidance = map[string]string{
+	"concise":  "Keep the body short: a one or two sentence summary and a brief list of the key changes.",
+	"standard": "Follow the body instructions as written.",
+	"detailed": "Write a thorough body: explain the motivation, walk through each significant change, and cover testing and risks.",
+}
+
+// Candidate is one option the user can pick from
+type Candidate struct {
+	Label     string
+	Title     string
+	Body      string
+	SessionID string
+}
+
+// ResolveTitleCandidates returns how many alternative titles to suggest, preferring the flag over the config
+func ResolveTitleCandidates(config *Config, flagValue int) int {
+	if flagValue > 0 {
+		return flagValue
+	}
+	if count := config.GetInt("title_candidates", DefaultTitleCandidates); count > 0 {
+		return count
+	}
+	return DefaultTitleCandidates
+}
+
+// ResolveBodyVariants returns the verbosity levels to generate, or nil for a single generation
+func ResolveBodyVariants(config *Config, enabled bool) []string {
+	variants := config.GetStringSlice("body_variants")
+	if len(variants) == 0 && enabled {
+		variants = DefaultBodyVariants
+	}
+	if len(variants) < 2 {
+		return nil
+	}
+	return variants
+}
+
+// GenerateBodyVariants generates the PR once per verbosity level in parallel.
+// Variants that fail are skipped; an error is returned only if all of them fail.
+func GenerateBodyVariants(config *Config, diff, background string, sections []PromptSection, variants []string) ([]Candidate, error) {
+	results := make([]*PRGenerationResult, len(variants))
+	errs := make([]error, len(variants))
+
+	var wg sync.WaitGroup
+	for i, variant := range variants {
+		wg.Add(1)
+		go func(i int, variant string) {
+			defer wg.Done()
+			variantSections := append(append([]PromptSection{}, sections...), PromptSection{
+				Title:   "BODY VERBOSITY",
+				Content: describeVerbosity(variant),
+			})
+			results[i], errs[i] = GeneratePRContentWithProvider(config, diff, background, variantSections)
+		}(i, variant)
+	}
+	wg.Wait()
+
+	var candidates []Candidate
+	var failures []string
+	for i, result := range results {
+		if errs[i] != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", variants[i], errs[i]))
+			continue
+		}
+		candidates = append(candidates, Candidate{
+			Label:     variants[i],
+			Title:     result.Title,
+			Body:      result.Body,
+			SessionID: result.SessionID,
+		})
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("all variants failed: %s", strings.Join(failures, "; "))
+	}
+	return candidates, nil
+}
+
+// describeVerbosity returns the prompt guidance for a verbosity level
+func describeVerbosity(variant string) string {
+	if guidance, ok := bodyVerbosityGuidance[strings.ToLower(variant)]; ok {
+		return guidance
+	}
+	return "Write the body in this style: " + variant
+}
@@ -0,0 +1,316 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ChangelogEntry is a single merged PR (or direct commit) in a changelog
+type ChangelogEntry struct {
+	Number      int    // PR number, 0 for direct commits
+	Title       string // Full PR title, e.g. "feat(auth): add JWT validation"
+	Type        string // Conventional-commit type, "" if the title does not follow the format
+	Scope       string
+	Description string // Title without the type and scope prefix
+	Body        string
+	URL         string
+	Breaking    bool
+}
+
+// ChangelogGroup holds the entries of one conventional-commit type
+type ChangelogGroup struct {
+	Type    string
+	Title   string
+	Entries []ChangelogEntry
+}
+
+// ChangelogData is passed to the changelog and release templates
+type ChangelogData struct {
+	Version  string
+	Date     string
+	From     string
+	To       string
+	Groups   []ChangelogGroup
+	Breaking []ChangelogEntry
+}
+
+// ChangelogOptions controls changelog generation
+type ChangelogOptions struct {
+	From    string
+	To      string
+	Version string // Heading of the section; defaults to To, or "Unreleased" for HEAD
+	Release bool   // Render the GitHub release template instead of the changelog template
+}
+
+// changelogSection is a conventional-commit type with its default section title
+type changelogSection struct {
+	Type  string
+	Title string
+}
+
+// changelogSections lists the sections in display order. Entries without a
+// known type are grouped under otherChangesType, which comes last.
+var changelogSections = []changelogSection{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance Improvements"},
+	{"refactor", "Code Refactoring"},
+	{"docs", "Documentation"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"style", "Styles"},
+	{"chore", "Chores"},
+	{"revert", "Reverts"},
+	{otherChangesType, "Other Changes"},
+}
+
+// otherChangesType groups entries without a known conventional-commit type
+const otherChangesType = "other"
+
+var (
+	mergeCommitPattern    = regexp.MustCompile(`^Merge pull request #(\d+) from `)
+	squashCommitPattern   = regexp.MustCompile(`\(#(\d+)\)\s*$`)
+	conventionalPattern   = regexp.MustCompile(`^(\w+)(?:\(([^)]+)\))?(!)?:\s*(.+)$`)
+	breakingFooterPattern = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:`)
+)
+
+// GenerateChangelog collects the PRs merged between opts.From and opts.To and renders them
+func GenerateChangelog(config *Config, opts ChangelogOptions) (string, error) {
+	if opts.To == "" {
+		opts.To = "HEAD"
+	}
+	if opts.Version == "" {
+		opts.Version = opts.To
+		if opts.To == "HEAD" {
+			opts.Version = "Unreleased"
+		}
+	}
+
+	entries, err := CollectChangelogEntries(config, opts.From, opts.To)
+	if err != nil {
+		return "", err
+	}
+
+	data := BuildChangelogData(config, entries)
+	data.Version = opts.Version
+	data.Date = time.Now().Format("2006-01-02")
+	data.From = opts.From
+	data.To = opts.To
+
+	templateText := config.ChangelogTemplate
+	if opts.Release {
+		templateText = config.ReleaseTemplate
+	}
+	return RenderChangelog(templateText, data)
+}
+
+// CollectChangelogEntries reads the first-parent history between from and to.
+// PR titles and bodies come from GitHub when changelog_source is "github", otherwise from the commits.
+func CollectChangelogEntries(config *Config, from, to string) ([]ChangelogEntry, error) {
+	commits, err := GetFirstParentCommits(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	useGitHub := config.GetString("changelog_source", "github") == "github"
+	var repo string
+	if useGitHub {
+		if err := checkGHCLI(); err != nil {
+			return nil, err
+		}
+		if owner, name, err := ResolveBaseRepo(config); err == nil {
+			repo = owner + "/" + name
+		}
+	}
+
+	var entries []ChangelogEntry
+	for _, commit := range commits {
+		entry, ok := entryFromCommit(commit)
+		if !ok {
+			continue
+		}
+
+		if useGitHub && entry.Number != 0 {
+			if pr, err := fetchMergedPR(repo, entry.Number); err == nil {
+				entry.Title, entry.Body, entry.URL = pr.Title, pr.Body, pr.URL
+			}
+		}
+
+		parseConventionalTitle(&entry)
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// entryFromCommit turns a merge or squash commit into a changelog entry.
+// Merges of other branches (e.g. "Merge branch 'main'") are skipped.
+func entryFromCommit(commit Commit) (ChangelogEntry, bool) {
+	if match := mergeCommitPattern.FindStringSubmatch(commit.Subject); match != nil {
+		number, _ := strconv.Atoi(match[1])
+		// GitHub puts the PR title in the first line of the merge commit body
+		title, body, _ := strings.Cut(strings.TrimSpace(commit.Body), "\n")
+		return ChangelogEntry{Number: number, Title: strings.TrimSpace(title), Body: strings.TrimSpace(body)}, true
+	}
+
+	if strings.HasPrefix(commit.Subject, "Merge ") {
+		return ChangelogEntry{}, false
+	}
+
+	entry := ChangelogEntry{Title: commit.Subject, Body: commit.Body}
+	if match := squashCommitPattern.FindStringSubmatch(commit.Subject); match != nil {
+		entry.Number, _ = strconv.Atoi(match[1])
+		entry.Title = strings.TrimSpace(squashCommitPattern.ReplaceAllString(commit.Subject, ""))
+	}
+	return entry, true
+}
+
+// parseConventionalTitle fills in type, scope, description and breaking flag from the title
+func parseConventionalTitle(entry *ChangelogEntry) {
+	entry.Description = entry.Title
+	if match := conventionalPattern.FindStringSubmatch(entry.Title); match != nil {
+		entry.Type = strings.ToLower(match[1])
+		entry.Scope = match[2]
+		entry.Breaking = match[3] == "!"
+		entry.Description = match[4]
+	}
+	if breakingFooterPattern.MatchString(entry.Body) {
+		entry.Breaking = true
+	}
+}
+
+// BuildChangelogData groups the entries by conventional-commit type in display order.
+// Section titles can be overridden with the changelog_section_titles config map.
+func BuildChangelogData(config *Config, entries []ChangelogEntry) ChangelogData {
+	titleOverrides := config.GetStringMap("changelog_section_titles")
+
+	byType := make(map[string][]ChangelogEntry)
+	known := make(map[string]bool, len(changelogSections))
+	for _, section := range changelogSections {
+		known[section.Type] = true
+	}
+
+	var data ChangelogData
+	for _, entry := range entries {
+		entryType := entry.Type
+		if !known[entryType] {
+			entryType = otherChangesType
+		}
+		byType[entryType] = append(byType[entryType], entry)
+		if entry.Breaking {
+			data.Breaking = append(data.Breaking, entry)
+		}
+	}
+
+	for _, section := range changelogSections {
+		if len(byType[section.Type]) == 0 {
+			continue
+		}
+		title := section.Title
+		if override, ok := titleOverrides[section.Type]; ok && override != "" {
+			title = override
+		}
+		data.Groups = append(data.Groups, ChangelogGroup{
+			Type:    section.Type,
+			Title:   title,
+			Entries: byType[section.Type],
+		})
+	}
+
+	return data
+}
+
+// RenderChangelog executes a changelog or release template with the given data
+func RenderChangelog(templateText string, data ChangelogData) (string, error) {
+	tmpl, err := template.New("changelog").Parse(templateText)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse changelog template: %w", err)
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, data); err != nil {
+		return "", fmt.Errorf("failed to render changelog template: %w", err)
+	}
+	return strings.TrimSpace(result.String()) + "\n", nil
+}
+
+// mergedPR holds the fields of a merged PR fetched from GitHub
+type mergedPR struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"`
+}
+
+// fetchMergedPR reads a PR's title, body and URL through gh CLI
+func fetchMergedPR(repo string, number int) (*mergedPR, error) {
+	args := []string{"pr", "view", strconv.Itoa(number), "--json", "title,body,url"}
+	if repo != "" {
+		args = append(args, "--repo", repo)
+	}
+
+	cmd := exec.Command("gh", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("gh pr view %d failed: %w", number, err)
+	}
+
+	var pr mergedPR
+	if err := json.Unmarshal(output, &pr); err != nil {
+		return nil, fmt.Errorf("failed to parse gh pr view output: %w", err)
+	}
+	return &pr, nil
+}
+
+// PrependChangelog inserts section into the changelog file above the previous release.
+// The file is created with a "# Changelog" heading if it does not exist.
+func PrependChangelog(path, section string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	content := string(existing)
+	if strings.TrimSpace(content) == "" {
+		content = "# Changelog\n\n" + section
+	} else if idx := strings.Index(content, "\n## "); idx != -1 {
+		content = content[:idx+1] + section + "\n" + content[idx+1:]
+	} else {
+		content = strings.TrimRight(content, "\n") + "\n\n" + section
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// PublishRelease creates a GitHub release for tag with the given notes
+func PublishRelease(config *Config, tag, notes string) (string, error) {
+	if err := checkGHCLI(); err != nil {
+		return "", err
+	}
+
+	args := []string{"release", "create", tag, "--title", tag, "--notes-file", "-"}
+	if owner, name, err := ResolveBaseRepo(config); err == nil {
+		args = append(args, "--repo", owner+"/"+name)
+	}
+
+	cmd := exec.Command("gh", args...)
+	cmd.Stdin = strings.NewReader(notes)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("gh release create failed: %s", string(exitError.Stderr))
+		}
+		return "", fmt.Errorf("failed to execute gh release create: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
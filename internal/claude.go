@@ -11,10 +11,20 @@ import (
 
 // claudeJSONResponse represents the JSON output from Claude CLI
 type claudeJSONResponse struct {
-	Type      string `json:"type"`
-	Result    string `json:"result"`
-	SessionID string `json:"session_id"`
-	IsError   bool   `json:"is_error"`
+	Type         string      `json:"type"`
+	Result       string      `json:"result"`
+	SessionID    string      `json:"session_id"`
+	IsError      bool        `json:"is_error"`
+	TotalCostUSD float64     `json:"total_cost_usd"`
+	Usage        claudeUsage `json:"usage"`
+}
+
+// claudeUsage represents the token counts in the Claude CLI JSON output
+type claudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 }
 
 const (
@@ -68,7 +78,7 @@ type PRGenerationResult struct {
 // GeneratePRContentWithClaude generates both PR title and body using Claude Code CLI
 // If refinement is provided, it will refine the previous output based on user feedback
 // The session ID from refinement context is used to continue the conversation
-func GeneratePRContentWithClaude(config *Config, diff, background string, refinement *RefinementContext) (*PRGenerationResult, error) {
+func GeneratePRContentWithClaude(config *Config, diff, background string, sections []PromptSection, refinement *RefinementContext) (*PRGenerationResult, error) {
 	// Check if Claude Code CLI is available
 	if _, err := exec.LookPath("claude"); err != nil {
 		return nil, fmt.Errorf("claude CLI not found. Please install Claude Code CLI first")
@@ -77,7 +87,7 @@ func GeneratePRContentWithClaude(config *Config, diff, background string, refine
 	// Filter and summarize the diff to manage token usage
 	filteredDiff := diff
 	if estimateTokens(diff) > 6000 { // Use constant from diff_filter.go
-		summary, err := FilterDiff(diff)
+		summary, err := FilterDiff(config, diff)
 		if err != nil {
 			return nil, fmt.Errorf("failed to filter diff: %w", err)
 		}
@@ -86,14 +96,16 @@ func GeneratePRContentWithClaude(config *Config, diff, background string, refine
 
 	var combinedPrompt string
 	var sessionID string
+	operation := "pr"
 
 	if refinement != nil {
 		// For refinement, we just send the feedback since we're continuing the session
 		combinedPrompt = buildRefinementPrompt(refinement)
 		sessionID = refinement.SessionID
+		operation = "pr-refine"
 	} else {
 		// Build full prompt for initial generation
-		combinedPrompt = buildCombinedPrompt(config, filteredDiff, background)
+		combinedPrompt = buildCombinedPrompt(config, filteredDiff, background, sections)
 	}
 
 	// Check token limit for combined prompt
@@ -102,7 +114,7 @@ func GeneratePRContentWithClaude(config *Config, diff, background string, refine
 	}
 
 	// Call Claude CLI (either new session or resume existing)
-	response, newSessionID, err := callClaudeCLI(combinedPrompt, sessionID)
+	response, newSessionID, err := callClaudeCLI(config, operation, combinedPrompt, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate PR content: %w", err)
 	}
@@ -120,10 +132,234 @@ func GeneratePRContentWithClaude(config *Config, diff, background string, refine
 	}, nil
 }
 
+// CommitGenerationResult holds the result of commit message generation
+type CommitGenerationResult struct {
+	Message   string
+	SessionID string
+}
+
+// GenerateCommitMessageWithClaude generates a commit message for the given diff using Claude Code CLI
+// If refinement is provided, the previous message is refined in the same session
+func GenerateCommitMessageWithClaude(config *Config, diff string, refinement *RefinementContext) (*CommitGenerationResult, error) {
+	// Check if Claude Code CLI is available
+	if _, err := exec.LookPath("claude"); err != nil {
+		return nil, fmt.Errorf("claude CLI not found. Please install Claude Code CLI first")
+	}
+
+	var prompt string
+	var sessionID string
+	operation := "commit"
+
+	if refinement != nil {
+		prompt = buildCommitRefinementPrompt(refinement)
+		sessionID = refinement.SessionID
+		operation = "commit-refine"
+	} else {
+		// Filter and summarize the diff to manage token usage
+		filteredDiff := diff
+		if estimateTokens(diff) > MaxTotalTokens {
+			summary, err := FilterDiff(config, diff)
+			if err != nil {
+				return nil, fmt.Errorf("failed to filter diff: %w", err)
+			}
+			filteredDiff = summary.FilteredDiff
+		}
+		prompt = buildCommitPrompt(config, filteredDiff)
+	}
+
+	if estimateTokens(prompt) > MaxInputTokens {
+		return nil, fmt.Errorf("commit prompt too large (%d estimated tokens, max %d)", estimateTokens(prompt), MaxInputTokens)
+	}
+
+	response, newSessionID, err := callClaudeCLI(config, operation, prompt, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	message := strings.TrimSpace(response)
+	if message == "" {
+		return nil, fmt.Errorf("empty commit message from Claude")
+	}
+
+	return &CommitGenerationResult{
+		Message:   message,
+		SessionID: newSessionID,
+	}, nil
+}
+
+// buildCommitPrompt constructs the prompt for generating a commit message
+func buildCommitPrompt(config *Config, diff string) string {
+	prompt := "Please write a git commit message for the following staged changes.\n\n"
+
+	prompt += "COMMIT MESSAGE REQUIREMENTS:\n"
+	prompt += config.CommitInstructions + "\n\n"
+
+	if config.CommitExample != "" {
+		prompt += "COMMIT MESSAGE EXAMPLE:\n" + config.CommitExample + "\n\n"
+	}
+
+	prompt += "GIT DIFF:\n" + diff + "\n\n"
+
+	prompt += "Respond with the commit message only, without code fences or commentary."
+
+	return prompt
+}
+
+// buildCommitRefinementPrompt constructs a prompt for refining a previously generated commit message
+func buildCommitRefinementPrompt(refinement *RefinementContext) string {
+	prompt := "Please refine the commit message based on my feedback:\n\n"
+	prompt += refinement.Feedback + "\n\n"
+	prompt += "Respond with the commit message only, without code fences or commentary."
+
+	return prompt
+}
+
+// TitleCandidatesResult holds alternative titles suggested for a PR
+type TitleCandidatesResult struct {
+	Titles    []string
+	SessionID string
+}
+
+// GenerateTitleCandidatesWithClaude asks Claude Code CLI for alternative titles in the PR's session
+func GenerateTitleCandidatesWithClaude(config *Config, refinement *RefinementContext, count int) (*TitleCandidatesResult, error) {
+	// Check if Claude Code CLI is available
+	if _, err := exec.LookPath("claude"); err != nil {
+		return nil, fmt.Errorf("claude CLI not found. Please install Claude Code CLI first")
+	}
+
+	response, newSessionID, err := callClaudeCLI(config, "titles", buildTitleCandidatesPrompt(refinement, count), refinement.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate title candidates: %w", err)
+	}
+
+	titles := parseTitleCandidates(response)
+	if len(titles) == 0 {
+		return nil, fmt.Errorf("could not extract any titles from Claude response")
+	}
+	if len(titles) > count {
+		titles = titles[:count]
+	}
+
+	return &TitleCandidatesResult{
+		Titles:    titles,
+		SessionID: newSessionID,
+	}, nil
+}
+
+// buildTitleCandidatesPrompt asks for alternative titles for the current version of the PR
+func buildTitleCandidatesPrompt(refinement *RefinementContext, count int) string {
+	prompt := ""
+	if refinement.BaseTitle != "" {
+		prompt += "This is the current version of the PR:\n"
+		prompt += "TITLE: " + refinement.BaseTitle + "\n"
+		prompt += "BODY:\n" + refinement.BaseBody + "\n\n"
+	}
+	prompt += fmt.Sprintf("Please suggest %d alternative PR titles for this PR that follow the same title instructions. ", count)
+	prompt += "Vary the wording and emphasis, and do not repeat the current title.\n\n"
+	prompt += "Respond with one title per line in this format and nothing else:\n"
+	prompt += "TITLE: [alternative title]"
+
+	return prompt
+}
+
+// parseTitleCandidates extracts the "TITLE:" lines from a title candidates response
+func parseTitleCandidates(response string) []string {
+	var titles []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		// Tolerate list markers such as "1. TITLE: ..." or "- TITLE: ..."
+		idx := strings.Index(line, "TITLE:")
+		if idx == -1 {
+			continue
+		}
+		title := strings.TrimSpace(line[idx+len("TITLE:"):])
+		if title == "" || seen[title] {
+			continue
+		}
+		seen[title] = true
+		titles = append(titles, title)
+	}
+	return titles
+}
+
+// InterpretBenchmarksWithClaude asks Claude Code CLI for a short reading of a benchmark comparison table
+func InterpretBenchmarksWithClaude(config *Config, table string) (string, error) {
+	// Check if Claude Code CLI is available
+	if _, err := exec.LookPath("claude"); err != nil {
+		return "", fmt.Errorf("claude CLI not found. Please install Claude Code CLI first")
+	}
+
+	response, _, err := callClaudeCLI(config, "bench", buildBenchmarkPrompt(table), "")
+	if err != nil {
+		return "", fmt.Errorf("failed to interpret benchmarks: %w", err)
+	}
+
+	interpretation := strings.TrimSpace(response)
+	if interpretation == "" {
+		return "", fmt.Errorf("empty benchmark interpretation from Claude")
+	}
+	return interpretation, nil
+}
+
+// buildBenchmarkPrompt asks for an interpretation that stays within what the table shows
+func buildBenchmarkPrompt(table string) string {
+	prompt := "These are benchstat-style results comparing benchmarks on the base revision (old) with this PR (new). "
+	prompt += "Each value is the median of several runs with its largest deviation. "
+	prompt += "\"~\" means the difference is not statistically significant (p >= 0.05).\n\n"
+	prompt += "```\n" + table + "\n```\n\n"
+	prompt += "Write a short interpretation for the PR description in 2 to 4 sentences. "
+	prompt += "Name the significant improvements and regressions with their size, say plainly if nothing changed significantly, "
+	prompt += "and do not speculate about causes or claim anything the table does not show.\n\n"
+	prompt += "Respond with the interpretation only, as plain Markdown prose without headings or code fences."
+
+	return prompt
+}
+
+// ReviewDiffWithClaude asks Claude Code CLI to review the diff and parses the structured findings
+func ReviewDiffWithClaude(config *Config, diff string) (*ReviewResult, error) {
+	// Check if Claude Code CLI is available
+	if _, err := exec.LookPath("claude"); err != nil {
+		return nil, fmt.Errorf("claude CLI not found. Please install Claude Code CLI first")
+	}
+
+	// Filter and summarize the diff to manage token usage
+	filteredDiff := diff
+	if estimateTokens(diff) > MaxTotalTokens {
+		summary, err := FilterDiff(config, diff)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter diff: %w", err)
+		}
+		filteredDiff = summary.FilteredDiff
+	}
+
+	prompt := buildReviewPrompt(config, filteredDiff)
+	if estimateTokens(prompt) > MaxInputTokens {
+		return nil, fmt.Errorf("review prompt too large (%d estimated tokens, max %d)", estimateTokens(prompt), MaxInputTokens)
+	}
+
+	response, sessionID, err := callClaudeCLI(config, "review", prompt, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to review diff: %w", err)
+	}
+
+	findings, err := parseReviewResponse(response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Claude response: %w", err)
+	}
+
+	return &ReviewResult{
+		Findings:  findings,
+		SessionID: sessionID,
+	}, nil
+}
+
 // callClaudeCLI executes the Claude Code CLI with the given prompt
 // If sessionID is provided, it resumes that session; otherwise starts a new one
 // Returns the response text and the session ID for future continuation
-func callClaudeCLI(prompt string, sessionID string) (response string, newSessionID string, err error) {
+// New sessions are answered from the response cache when the same prompt was sent recently
+// The token usage and cost of each call are recorded under operation, e.g. "pr" or "review"
+func callClaudeCLI(config *Config, operation, prompt string, sessionID string) (response string, newSessionID string, err error) {
 	ctx := context.Background()
 
 	// Validate prompt is not empty
@@ -131,6 +367,19 @@ func callClaudeCLI(prompt string, sessionID string) (response string, newSession
 		return "", "", fmt.Errorf("empty prompt provided to Claude CLI")
 	}
 
+	// Resumed sessions are not cached: the same feedback should get a fresh answer
+	var cache *ResponseCache
+	var cacheKey string
+	if sessionID == "" {
+		cache = NewResponseCache(config)
+	}
+	if cache != nil {
+		cacheKey = ResponseCacheKey(config, prompt)
+		if cached, ok := cache.Get(cacheKey); ok {
+			return cached.Response, cached.SessionID, nil
+		}
+	}
+
 	// Build command arguments
 	args := []string{"-p", "--output-format", "json"}
 	if sessionID != "" {
@@ -155,6 +404,17 @@ func callClaudeCLI(prompt string, sessionID string) (response string, newSession
 		return "", "", fmt.Errorf("failed to parse Claude JSON response: %w, raw output: %s", err, string(output))
 	}
 
+	// Failed calls are billed too, so record usage before checking for errors
+	RecordUsage(config, UsageRecord{
+		Operation:           operation,
+		Provider:            "claude",
+		InputTokens:         jsonResp.Usage.InputTokens,
+		OutputTokens:        jsonResp.Usage.OutputTokens,
+		CacheCreationTokens: jsonResp.Usage.CacheCreationInputTokens,
+		CacheReadTokens:     jsonResp.Usage.CacheReadInputTokens,
+		CostUSD:             jsonResp.TotalCostUSD,
+	})
+
 	if jsonResp.IsError {
 		return "", "", fmt.Errorf("Claude returned an error: %s", jsonResp.Result)
 	}
@@ -163,11 +423,15 @@ func callClaudeCLI(prompt string, sessionID string) (response string, newSession
 		return "", "", fmt.Errorf("empty response from Claude CLI")
 	}
 
+	if cache != nil {
+		cache.Put(cacheKey, jsonResp.Result, jsonResp.SessionID)
+	}
+
 	return jsonResp.Result, jsonResp.SessionID, nil
 }
 
 // buildCombinedPrompt constructs a single prompt for generating both PR title and body
-func buildCombinedPrompt(config *Config, diff, background string) string {
+func buildCombinedPrompt(config *Config, diff, background string, sections []PromptSection) string {
 	prompt := "Please generate both a PR title and PR body based on the following requirements and git diff.\n\n"
 
 	if strings.TrimSpace(background) != "" {
@@ -175,6 +439,14 @@ func buildCombinedPrompt(config *Config, diff, background string) string {
 		prompt += background + "\n\n"
 	}
 
+	for _, section := range sections {
+		if strings.TrimSpace(section.Content) == "" {
+			continue
+		}
+		prompt += section.Title + ":\n"
+		prompt += section.Content + "\n\n"
+	}
+
 	prompt += "TITLE REQUIREMENTS:\n"
 	prompt += config.TitleInstructions + "\n\n"
 
@@ -201,7 +473,14 @@ func buildCombinedPrompt(config *Config, diff, background string) string {
 // buildRefinementPrompt constructs a prompt for refining a previously generated PR
 // Since we're continuing the session, Claude already has context from the previous exchange
 func buildRefinementPrompt(refinement *RefinementContext) string {
-	prompt := "Please refine the PR title and body based on my feedback:\n\n"
+	prompt := ""
+	if refinement.BaseTitle != "" {
+		// The user went back to an earlier version, which is not the last response in the session
+		prompt += "Please start from this earlier version instead of your last response:\n"
+		prompt += "TITLE: " + refinement.BaseTitle + "\n"
+		prompt += "BODY:\n" + refinement.BaseBody + "\n\n"
+	}
+	prompt += "Please refine the PR title and body based on my feedback:\n\n"
 	prompt += refinement.Feedback + "\n\n"
 	prompt += "Please respond in the same format as before:\n"
 	prompt += "TITLE: [your refined title]\n"
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GenerateCommit creates a commit for the staged changes with a generated message.
+// This is the entrypoint for the commit subcommand.
+func GenerateCommit() {
+	InitializeUI()
+
+	// Load configuration with spinner
+	var config *Config
+	err := RunSpinnerWithTask("Loading configuration", func() error {
+		var err error
+		config, err = LoadConfig()
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to load config", err)
+		return
+	}
+
+	// Get staged diff with spinner
+	var diff string
+	err = RunSpinnerWithTask("Analyzing staged changes", func() error {
+		var err error
+		diff, err = GetStagedDiff()
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to get staged diff", err)
+		return
+	}
+	if diff == "" {
+		fmt.Println(warningStyle.Render("⚠️  No staged changes. Stage files with 'git add' first."))
+		return
+	}
+
+	// Generate commit message with spinner
+	var result *CommitGenerationResult
+	err = RunSpinnerWithTask("Generating commit message", func() error {
+		var err error
+		result, err = GenerateCommitMessageWithProvider(config, diff)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to generate commit message", err)
+		return
+	}
+	ShowCacheHits()
+
+	message, sessionID := result.Message, result.SessionID
+
+	// Display generated message and handle refinement loop
+	for {
+		ShowCommitMessage(message)
+
+		switch AskCommitAction() {
+		case CommitChoiceAccept:
+			err = RunSpinnerWithTask("Committing changes", func() error {
+				return CommitStaged(message)
+			})
+			if err != nil {
+				ShowError("Failed to commit changes", err)
+				return
+			}
+			ShowSuccess("Changes committed")
+			ShowRunUsage()
+			return
+		case CommitChoiceRefine:
+			feedback := AskRefinementFeedback()
+			if feedback == "" {
+				continue
+			}
+
+			refinement := &RefinementContext{
+				SessionID: sessionID,
+				Feedback:  feedback,
+			}
+			err = RunSpinnerWithTask("Refining commit message", func() error {
+				var err error
+				result, err = RefineCommitMessageWithProvider(config, diff, refinement)
+				return err
+			})
+			if err != nil {
+				ShowError("Failed to refine commit message", err)
+				return
+			}
+			message, sessionID = result.Message, result.SessionID
+		case CommitChoiceEdit:
+			edited, err := EditText(message)
+			if err != nil {
+				ShowError("Failed to edit commit message", err)
+				continue
+			}
+			if edited == "" {
+				fmt.Println(warningStyle.Render("⚠️  Empty message, keeping the previous one"))
+				continue
+			}
+			message = edited
+		case CommitChoiceCancel:
+			fmt.Println(infoStyle.Render("ℹ️  Commit cancelled by user"))
+			return
+		}
+	}
+}
+
+// RunCommitHook fills in the commit message file when called as a prepare-commit-msg hook.
+// source is the second hook argument; when git already has a message (e.g. -m, merge,
+// amend) the file is left untouched.
+func RunCommitHook(messageFile, source string) error {
+	if source != "" {
+		return nil
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	diff, err := GetStagedDiff()
+	if err != nil {
+		return fmt.Errorf("failed to get staged diff: %w", err)
+	}
+	if diff == "" {
+		return nil
+	}
+
+	result, err := GenerateCommitMessageWithProvider(config, diff)
+	if err != nil {
+		return err
+	}
+
+	// Keep git's comment lines (status summary etc.) below the generated message
+	existing, err := os.ReadFile(messageFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", messageFile, err)
+	}
+
+	content := result.Message + "\n"
+	if trimmed := strings.TrimLeft(string(existing), "\n"); trimmed != "" {
+		content += "\n" + trimmed
+	}
+
+	if err := os.WriteFile(messageFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", messageFile, err)
+	}
+	return nil
+}
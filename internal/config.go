@@ -6,18 +6,60 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
 //go:embed templates/*
 var templateFiles embed.FS
 
+// repoConfigFile is the optional per-repository config file, placed at the
+// repository root. Its keys override the ones in the main config.json.
+const repoConfigFile = ".prgen.json"
+
+// repoConfigKeys are the keys a repository may override. The repository config comes
+// with every clone, so keys that run commands (test_command, bench_command) or send
+// credentials (issue_trackers, whose fetch headers expand environment variables) are
+// only read from the user's config.json.
+var repoConfigKeys = map[string]bool{
+	"pr_state":                 true,
+	"merge_method":             true,
+	"push_remote":              true,
+	"base_remote":              true,
+	"base_branch":              true,
+	"detect_stacks":            true,
+	"stack_parents":            true,
+	"title_candidates":         true,
+	"body_variants":            true,
+	"reviewer_notes":           true,
+	"diff_context_lines":       true,
+	"file_priority_weights":    true,
+	"dependency_table":         true,
+	"max_dependency_changes":   true,
+	"detect_scopes":            true,
+	"scopes":                   true,
+	"max_scopes":               true,
+	"changelog_source":         true,
+	"changelog_section_titles": true,
+	"fetch_linked_issues":      true,
+	"max_issue_chars":          true,
+	"bench_auto":               true,
+	"bench_timeout_minutes":    true,
+}
+
 type Config struct {
-	ConfigDir         string
-	MainConfig        map[string]interface{}
-	BodyInstructions  string
-	TitleInstructions string
-	BodyExample       string
-	TitleExample      string
+	ConfigDir          string
+	RepoConfigPath     string
+	IgnoredRepoKeys    []string // Keys of the repository config that only the user config may set
+	MainConfig         map[string]interface{}
+	BodyInstructions   string
+	TitleInstructions  string
+	BodyExample        string
+	TitleExample       string
+	CommitInstructions string
+	CommitExample      string
+	ChangelogTemplate  string
+	ReleaseTemplate    string
+	ReviewInstructions string
 }
 
 func GetConfigDir() (string, error) {
@@ -50,6 +92,11 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to load config files: %w", err)
 	}
 
+	err = config.loadRepoConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load repository config: %w", err)
+	}
+
 	return config, nil
 }
 
@@ -65,6 +112,11 @@ func (c *Config) ensureConfigExists() error {
 		"title_instructions.md",
 		"body_example.md",
 		"title_example.md",
+		"commit_instructions.md",
+		"commit_example.md",
+		"changelog_template.md",
+		"release_template.md",
+		"review_instructions.md",
 	}
 
 	for _, filename := range configFiles {
@@ -110,10 +162,15 @@ func (c *Config) loadFiles() error {
 
 	// Load instructions and examples
 	fileLoaders := map[string]*string{
-		"body_instructions.md":  &c.BodyInstructions,
-		"title_instructions.md": &c.TitleInstructions,
-		"body_example.md":       &c.BodyExample,
-		"title_example.md":      &c.TitleExample,
+		"body_instructions.md":   &c.BodyInstructions,
+		"title_instructions.md":  &c.TitleInstructions,
+		"body_example.md":        &c.BodyExample,
+		"title_example.md":       &c.TitleExample,
+		"commit_instructions.md": &c.CommitInstructions,
+		"commit_example.md":      &c.CommitExample,
+		"changelog_template.md":  &c.ChangelogTemplate,
+		"release_template.md":    &c.ReleaseTemplate,
+		"review_instructions.md": &c.ReviewInstructions,
 	}
 
 	for filename, target := range fileLoaders {
@@ -128,6 +185,122 @@ func (c *Config) loadFiles() error {
 	return nil
 }
 
+// loadRepoConfig merges the allowed keys of the repository's .prgen.json over the main config, if present
+func (c *Config) loadRepoConfig() error {
+	repoRoot, err := GetRepoRoot()
+	if err != nil {
+		// Not inside a git repository, nothing to merge
+		return nil
+	}
+
+	repoConfigPath := filepath.Join(repoRoot, repoConfigFile)
+	data, err := os.ReadFile(repoConfigPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", repoConfigFile, err)
+	}
+
+	var repoConfig map[string]interface{}
+	err = json.Unmarshal(data, &repoConfig)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", repoConfigFile, err)
+	}
+
+	if c.MainConfig == nil {
+		c.MainConfig = map[string]interface{}{}
+	}
+	for key, value := range repoConfig {
+		if !repoConfigKeys[key] {
+			c.IgnoredRepoKeys = append(c.IgnoredRepoKeys, key)
+			continue
+		}
+		c.MainConfig[key] = value
+	}
+	sort.Strings(c.IgnoredRepoKeys)
+	c.RepoConfigPath = repoConfigPath
+
+	return nil
+}
+
+// GetString returns the string value of key in the main config, or def if unset
+func (c *Config) GetString(key, def string) string {
+	if value, ok := c.MainConfig[key].(string); ok && value != "" {
+		return value
+	}
+	return def
+}
+
+// GetBool returns the boolean value of key in the main config, or def if unset
+func (c *Config) GetBool(key string, def bool) bool {
+	if value, ok := c.MainConfig[key].(bool); ok {
+		return value
+	}
+	return def
+}
+
+// GetInt returns the integer value of key in the main config, or def if unset
+func (c *Config) GetInt(key string, def int) int {
+	// JSON numbers are decoded as float64
+	if value, ok := c.MainConfig[key].(float64); ok {
+		return int(value)
+	}
+	return def
+}
+
+// GetStringSlice returns the string list stored under key in the main config
+func (c *Config) GetStringSlice(key string) []string {
+	values, ok := c.MainConfig[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var result []string
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+// GetStringMap returns the string-to-string object stored under key in the main config
+func (c *Config) GetStringMap(key string) map[string]string {
+	values, ok := c.MainConfig[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make(map[string]string, len(values))
+	for name, value := range values {
+		if str, ok := value.(string); ok {
+			result[name] = str
+		}
+	}
+	return result
+}
+
+// Decode unmarshals the value stored under key in the main config into target.
+// It returns false if the key is not set.
+func (c *Config) Decode(key string, target interface{}) (bool, error) {
+	value, ok := c.MainConfig[key]
+	if !ok || value == nil {
+		return false, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config key %s: %w", key, err)
+	}
+	err = json.Unmarshal(data, target)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for config key %s: %w", key, err)
+	}
+
+	return true, nil
+}
+
 func (c *Config) GetConfigPath() string {
 	return filepath.Join(c.ConfigDir, "config.json")
 }
@@ -147,3 +320,23 @@ func (c *Config) GetBodyExamplePath() string {
 func (c *Config) GetTitleExamplePath() string {
 	return filepath.Join(c.ConfigDir, "title_example.md")
 }
+
+func (c *Config) GetCommitInstructionsPath() string {
+	return filepath.Join(c.ConfigDir, "commit_instructions.md")
+}
+
+func (c *Config) GetCommitExamplePath() string {
+	return filepath.Join(c.ConfigDir, "commit_example.md")
+}
+
+func (c *Config) GetChangelogTemplatePath() string {
+	return filepath.Join(c.ConfigDir, "changelog_template.md")
+}
+
+func (c *Config) GetReleaseTemplatePath() string {
+	return filepath.Join(c.ConfigDir, "release_template.md")
+}
+
+func (c *Config) GetReviewInstructionsPath() string {
+	return filepath.Join(c.ConfigDir, "review_instructions.md")
+}
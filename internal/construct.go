@@ -4,11 +4,347 @@ import (
 	"fmt"
 )
 
+// RunOptions holds per-run settings passed in from the command line.
+// Empty values fall back to the config.
+type RunOptions struct {
+	PRState        string
+	MergeMethod    string
+	NonInteractive bool // Accept the generated content without prompting, e.g. from a git hook
+	Review         bool // Add an AI self-review as a "Reviewer Notes" section
+	Titles         int  // Number of alternative titles to suggest, 0 for the config value
+	BodyVariants   bool // Generate one candidate per body verbosity level and pick one
+	SkipTests      bool // Do not run the configured test command
+	Bench          bool // Compare benchmarks with the base branch even if the change is not labelled as a performance change
+}
+
+// prRun holds the settings shared by new and resumed PR generation runs
+type prRun struct {
+	config    *Config
+	opts      RunOptions
+	prOptions PROptions
+	remotes   Remotes
+	base      PRBase
+	branch    string
+}
+
 // Construct creates the PR proposal using LLMs.
 // This is the main entrypoint for PR generation.
-func Construct() {
+func Construct(opts RunOptions) {
+	run := startRun(opts)
+	if run == nil {
+		return
+	}
+	config, remotes, base, branch := run.config, run.remotes, run.base, run.branch
+
+	// Without a user to ask, never create a second PR for the same branch
+	if opts.NonInteractive {
+		existingURL, err := findExistingPR(remotes, branch)
+		if err != nil {
+			ShowError("Failed to look for an existing PR", err)
+			return
+		}
+		if existingURL != "" {
+			fmt.Println(infoStyle.Render("ℹ️  A PR already exists for this branch: " + existingURL))
+			return
+		}
+	}
+
+	// Handle uncommitted changes before analysing the branch
+	includeStaged, ok := handleUncommittedChanges(config, opts.NonInteractive)
+	if !ok {
+		return
+	}
+
+	// Get git diff with spinner
+	var diff string
+	err := RunSpinnerWithTask("Analyzing git changes", func() error {
+		var err error
+		if includeStaged {
+			diff, err = GetDiffIncludingStaged(base.Branch)
+		} else {
+			diff, err = GetDiff(base.Branch)
+		}
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to get diff", err)
+		return
+	}
+
+	// Check if there are changes
+	ShowDiffInfo(len(diff))
+	if diff == "" {
+		return
+	}
+
+	// Find issue keys in the branch name and commit trailers
+	var issueRefs []IssueRef
+	err = RunSpinnerWithTask("Looking for linked issues", func() error {
+		var err error
+		issueRefs, err = FindIssueRefs(config, base.Branch)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to find linked issues", err)
+		// Don't return here - the PR can be created without issue links
+	}
+	ShowIssueRefs(issueRefs)
+
+	// Find the other PRs of the stack to link them from this one
+	var stack []StackEntry
+	err = RunSpinnerWithTask("Looking for stacked branches", func() error {
+		var err error
+		stack, err = FindStackPRs(config, remotes, base, branch)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to look up the stack", err)
+		// Don't return here - the PR can be created without stack navigation
+	}
+
+	var sections []PromptSection
+	if len(issueRefs) > 0 {
+		// Fetch the issue titles and descriptions for extra context
+		var linkedIssues []LinkedIssue
+		err = RunSpinnerWithTask("Fetching linked issue content", func() error {
+			var err error
+			linkedIssues, err = FetchLinkedIssues(config, issueRefs)
+			return err
+		})
+		if err != nil {
+			ShowError("Failed to fetch some linked issues", err)
+			// Don't return here - whatever was fetched is still used
+		}
+		maxChars := config.GetInt("max_issue_chars", DefaultMaxIssueChars)
+		sections = append(sections, PromptSection{Title: "LINKED ISSUES", Content: BuildIssuePromptSection(issueRefs, linkedIssues, maxChars)})
+	}
+
+	// Describe renames, binary files and mode changes, which filtering can hide
+	if operations := BuildFileOperationsSection(diff); operations != "" {
+		sections = append(sections, PromptSection{Title: "FILE OPERATIONS", Content: operations})
+	}
+
+	// Offer the scopes of the changed packages for the conventional-commit title
+	scopes, err := AffectedScopes(config, diff)
+	if err != nil {
+		ShowError("Failed to detect scopes", err)
+		// Don't return here - the title can be written without known scopes
+	}
+	if len(scopes) > 0 {
+		ShowScopes(scopes, config.GetInt("max_scopes", DefaultMaxScopes))
+		sections = append(sections, PromptSection{Title: "SCOPES", Content: BuildScopePromptSection(scopes)})
+	}
+
+	// Summarize dependency version changes instead of sending lockfile diffs
+	dependencies := SummarizeDependencies(diff)
+	maxDependencies := config.GetInt("max_dependency_changes", DefaultMaxDependencyChanges)
+	if len(dependencies) > 0 {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("📦 %d dependency change(s)", len(dependencies))))
+		sections = append(sections, PromptSection{Title: "DEPENDENCIES", Content: BuildDependencyPromptSection(dependencies, maxDependencies)})
+	}
+	promptDiff := StripLockfileDiffs(diff, dependencies)
+
+	// Run the configured tests so the Testing section is based on facts
+	if config.GetString("test_command", "") != "" && !opts.SkipTests {
+		report, baseReport := runTestsForPR(config, base.Branch)
+		if report != nil {
+			ShowTestReport(report, baseReport)
+			if !report.Succeeded {
+				if config.GetString("test_failure", TestFailureWarn) == TestFailureRefuse {
+					ShowError("Tests failed", fmt.Errorf("fix the failing tests or run with --skip-tests"))
+					return
+				}
+				fmt.Println(warningStyle.Render("⚠️  Tests failed, the PR will say so"))
+			}
+			sections = append(sections, PromptSection{Title: "TEST RESULTS", Content: BuildTestPromptSection(report, baseReport)})
+		}
+	}
+
+	// Compare benchmarks with the base branch for performance changes
+	var benchmark *BenchmarkReport
+	if config.GetString("bench_command", "") != "" && (opts.Bench || (config.GetBool("bench_auto", true) && IsPerformanceChange(branch, base.Branch))) {
+		benchmark = runBenchmarksForPR(config, base.Branch)
+		if benchmark != nil {
+			sections = append(sections, PromptSection{Title: "BENCHMARKS", Content: BuildBenchmarkPromptSection(benchmark)})
+		}
+	}
+
+	// Collect background information from user
+	var backgroundInfo string
+	if !opts.NonInteractive {
+		backgroundInfo = AskBackgroundInfo()
+	}
+
+	// Generate PR content with spinner, optionally as several variants to pick from
+	var result *PRGenerationResult
+	if variants := ResolveBodyVariants(config, opts.BodyVariants); variants != nil {
+		var candidates []Candidate
+		err = RunSpinnerWithTask(fmt.Sprintf("Generating %d PR variants", len(variants)), func() error {
+			var err error
+			candidates, err = GenerateBodyVariants(config, promptDiff, backgroundInfo, sections, variants)
+			return err
+		})
+		if err != nil {
+			ShowError("Failed to generate PR content", err)
+			return
+		}
+		ShowCacheHits()
+
+		picked := candidates[0]
+		if !opts.NonInteractive {
+			if index := AskCandidate("Pick a variant", candidates, true); index >= 0 {
+				picked = candidates[index]
+			}
+		}
+		result = &PRGenerationResult{Title: picked.Title, Body: picked.Body, SessionID: picked.SessionID}
+	} else {
+		err = RunSpinnerWithTask("Generating PR content", func() error {
+			var err error
+			result, err = GeneratePRContentWithProvider(config, promptDiff, backgroundInfo, sections)
+			return err
+		})
+		if err != nil {
+			ShowError("Failed to generate PR content", err)
+			return
+		}
+		ShowCacheHits()
+	}
+
+	// Optionally self-review the diff for a "Reviewer Notes" section and PR comments
+	reviewNotes := opts.Review || config.GetBool("reviewer_notes", false)
+	var findings []ReviewFinding
+	if reviewNotes || config.GetBool("post_review_comments", false) {
+		var review *ReviewResult
+		err = RunSpinnerWithTask("Reviewing changes", func() error {
+			var err error
+			review, err = ReviewDiffWithProvider(config, promptDiff)
+			return err
+		})
+		if err != nil {
+			ShowError("Failed to review changes", err)
+			// Don't return here - the PR can be created without review notes
+		} else {
+			ShowCacheHits()
+			findings = review.Findings
+			ShowReviewFindings(findings)
+		}
+	}
+
+	ShowRunUsage()
+
+	// Record the run so it can be resumed if pushing or creating the PR fails
+	session := NewSession(diff, backgroundInfo)
+	session.LLMSessionID = result.SessionID
+	session.IssueRefs = issueRefs
+	session.ReviewNotes = reviewNotes
+	session.Findings = findings
+	session.Dependencies = dependencies
+	session.Stack = stack
+	session.Benchmark = benchmark
+	session.Staged = includeStaged
+	session.Sections = sections
+	session.AddIteration("", result.Title, finalizeBody(run, session, result.Body))
+	session.LLMVersion = session.Version()
+
+	finishSession(run, session, promptDiff, sections)
+}
+
+// runTestsForPR runs the tests on the working tree and, for coverage deltas, on the base branch.
+// The base result is nil when it is disabled or could not be measured.
+func runTestsForPR(config *Config, baseBranch string) (*TestReport, *TestReport) {
+	root, err := GetRepoRoot()
+	if err != nil {
+		ShowError("Failed to find repository root", err)
+		return nil, nil
+	}
+
+	var report *TestReport
+	err = RunSpinnerWithTask("Running tests", func() error {
+		var err error
+		report, err = RunTests(config, root)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to run tests", err)
+		// Don't return here - the PR can be created without test results
+		return nil, nil
+	}
+	if len(report.Coverage) == 0 || !config.GetBool("test_base_coverage", true) {
+		return report, nil
+	}
+
+	var base *TestReport
+	err = RunSpinnerWithTask(fmt.Sprintf("Measuring coverage on %s", baseBranch), func() error {
+		mergeBase, err := getMergeBase(baseBranch, "HEAD")
+		if err != nil {
+			return err
+		}
+		base, err = RunBaseTests(config, mergeBase)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to measure base coverage", err)
+		// Don't return here - the current results are still useful
+		return report, nil
+	}
+	return report, base
+}
+
+// runBenchmarksForPR runs the benchmarks at the merge-base and at HEAD and compares them.
+// It returns nil when the benchmarks could not be run.
+func runBenchmarksForPR(config *Config, baseBranch string) *BenchmarkReport {
+	mergeBase, err := getMergeBase(baseBranch, "HEAD")
+	if err != nil {
+		ShowError("Failed to find the merge-base for benchmarks", err)
+		return nil
+	}
+
+	// The revisions run one after the other so they do not compete for the CPU
+	var base, head *BenchmarkRun
+	err = RunSpinnerWithTask(fmt.Sprintf("Running benchmarks on %s", baseBranch), func() error {
+		var err error
+		base, err = RunBenchmarks(config, mergeBase)
+		return err
+	})
+	if err == nil {
+		err = RunSpinnerWithTask("Running benchmarks on HEAD", func() error {
+			var err error
+			head, err = RunBenchmarks(config, "HEAD")
+			return err
+		})
+	}
+	if err != nil {
+		ShowError("Failed to run benchmarks", err)
+		// Don't return here - the PR can be created without benchmarks
+		return nil
+	}
+
+	comparisons := CompareBenchmarks(base, head)
+	report := &BenchmarkReport{
+		Command:     config.GetString("bench_command", ""),
+		Comparisons: comparisons,
+		Table:       FormatBenchmarkTable(comparisons),
+	}
+	ShowBenchmark(report)
+
+	err = RunSpinnerWithTask("Interpreting benchmark results", func() error {
+		var err error
+		report.Interpretation, err = InterpretBenchmarksWithProvider(config, report.Table)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to interpret benchmarks", err)
+		// Don't return here - the table is attached without an interpretation
+	}
+	return report
+}
+
+// startRun loads the configuration and checks the branch a PR would be created from.
+// It returns nil if prgen should stop.
+func startRun(opts RunOptions) *prRun {
 	// Initialize beautiful UI
 	InitializeUI()
+	SetNonInteractive(opts.NonInteractive)
 	ShowStartupBanner()
 
 	// Load configuration with spinner
@@ -20,54 +356,164 @@ func Construct() {
 	})
 	if err != nil {
 		ShowError("Failed to load config", err)
-		return
+		return nil
 	}
 
 	// Show configuration summary
 	ShowConfigSummary(config)
 
-	// Get git diff with spinner
-	var diff string
-	err = RunSpinnerWithTask("Analyzing git changes", func() error {
+	// Resolve how the PR will be created (draft, ready, auto-merge)
+	prOptions, err := ResolvePROptions(config, opts.PRState, opts.MergeMethod)
+	if err != nil {
+		ShowError("Invalid PR options", err)
+		return nil
+	}
+
+	// Resolve where the branch is pushed and where the PR is opened
+	remotes, err := ResolveRemotes(config)
+	if err != nil {
+		ShowError("Failed to resolve git remotes", err)
+		return nil
+	}
+	ShowRemotes(remotes)
+
+	// Stacked branches are diffed against and opened on their parent branch
+	current, _ := GetCurrentBranch()
+	base, err := ResolveBase(config, current)
+	if err != nil {
+		ShowError("Failed to find the parent branch, using "+base.Trunk, err)
+		// Don't return here - the trunk is a safe base
+	}
+	if base.Source != BaseFromTrunk && remotes.IsFork() {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠️  Stacked PRs need their parent branch in '%s', opening the PR against %s instead of %s", remotes.Base, base.Trunk, base.Branch)))
+		base.Branch, base.Source = base.Trunk, BaseFromTrunk
+	}
+	ShowBaseBranch(base)
+
+	// Refuse early if there is no branch a PR could be created from
+	branch, err := ValidatePushBranch(base.Trunk)
+	if err != nil {
+		ShowError("Cannot create a PR from here", err)
+		return nil
+	}
+
+	return &prRun{
+		config:    config,
+		opts:      opts,
+		prOptions: prOptions,
+		remotes:   remotes,
+		base:      base,
+		branch:    branch,
+	}
+}
+
+// ResumeSession continues a saved session: the latest unfinished one for the current branch,
+// or the session with the given ID. This is the entrypoint for the resume subcommand.
+func ResumeSession(id string, opts RunOptions) {
+	run := startRun(opts)
+	if run == nil {
+		return
+	}
+
+	history := NewHistoryStore(run.config)
+	var session *Session
+	err := RunSpinnerWithTask("Loading saved session", func() error {
 		var err error
-		diff, err = GetDiff()
+		if id != "" {
+			session, err = history.Load(id)
+			return err
+		}
+		repoRoot, err := GetRepoRoot()
+		if err != nil {
+			return err
+		}
+		session, err = history.Latest(repoRoot, run.branch)
 		return err
 	})
 	if err != nil {
-		ShowError("Failed to get diff", err)
+		ShowError("Failed to load session", err)
 		return
 	}
 
-	// Check if there are changes
-	ShowDiffInfo(len(diff))
-	if diff == "" {
+	if session.Status == SessionCreated {
+		fmt.Println(infoStyle.Render("ℹ️  A PR was already created from this session: " + session.PRURL))
 		return
 	}
+	if session.Branch != run.branch {
+		ShowError("Cannot resume session", fmt.Errorf("session %s was created on branch %s, check it out first", session.ID, session.Branch))
+		return
+	}
+	fmt.Println(infoStyle.Render(fmt.Sprintf("ℹ️  Resuming session %s (%d iteration(s))", session.ID, len(session.Iterations))))
+	session.Status = SessionInProgress
 
-	// Collect background information from user
-	backgroundInfo := AskBackgroundInfo()
-
-	// Generate PR content with spinner
-	var result *PRGenerationResult
-	err = RunSpinnerWithTask("Generating PR content", func() error {
+	// The diff is still needed for refinement, and tells whether the content is stale.
+	// It is read the same way as when the session was generated.
+	var diff string
+	err = RunSpinnerWithTask("Analyzing git changes", func() error {
 		var err error
-		result, err = GeneratePRContentWithProvider(config, diff, backgroundInfo)
+		if session.Staged {
+			diff, err = GetDiffIncludingStaged(run.base.Branch)
+		} else {
+			diff, err = GetDiff(run.base.Branch)
+		}
 		return err
 	})
 	if err != nil {
-		ShowError("Failed to generate PR content", err)
+		ShowError("Failed to get diff", err)
 		return
 	}
+	if HashDiff(diff) != session.DiffHash {
+		fmt.Println(warningStyle.Render("⚠️  The branch has changed since this session was generated, refine the content to bring it up to date"))
+	}
 
-	// Track session ID for conversation continuity
-	title, body, sessionID := result.Title, result.Body, result.SessionID
+	finishSession(run, session, StripLockfileDiffs(diff, session.Dependencies), session.Sections)
+}
+
+// finalizeBody adds the sections prgen manages itself to a generated body
+func finalizeBody(run *prRun, session *Session, body string) string {
+	config := run.config
+	body = AppendIssueSection(body, session.IssueRefs)
+	if config.GetBool("dependency_table", false) {
+		body = AppendDependencySection(body, session.Dependencies, config.GetInt("max_dependency_changes", DefaultMaxDependencyChanges))
+	}
+	if session.ReviewNotes {
+		body = AppendReviewSection(body, session.Findings)
+	}
+	body = AppendBenchmarkSection(body, session.Benchmark)
+	return AppendStackSection(body, session.Stack, run.base.Trunk, session.Branch)
+}
+
+// finishSession runs the refinement loop, pushes the branch and creates the PR.
+// The session is saved after every step so a failed run can be resumed.
+func finishSession(run *prRun, session *Session, diff string, sections []PromptSection) {
+	config, opts, remotes, branch := run.config, run.opts, run.remotes, run.branch
+	prOptions := run.prOptions
+
+	history := NewHistoryStore(config)
+	saveSession := func() {
+		if err := history.Save(session); err != nil {
+			ShowError("Failed to save session history", err)
+			// Don't return here - history is a convenience
+		}
+	}
+	saveSession()
 
 	// Display generated content and handle refinement loop
 	for {
-		ShowGeneratedContent(title, body)
+		current := session.Current()
+		ShowGeneratedContent(current.Title, current.Body)
+		if opts.NonInteractive {
+			break
+		}
+		if len(session.Iterations) > 1 {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("ℹ️  Version %d of %d", session.Version(), len(session.Iterations))))
+		}
+		if parent, ok := session.Parent(); ok {
+			ShowIterationDiff(current.Parent, parent, current)
+		}
 
 		// Ask user what they want to do
-		choice := AskRefinementOrAccept()
+		choice := AskRefinementOrAccept(prOptions, session.Version(), len(session.Iterations))
 
 		switch choice {
 		case ChoiceAccept:
@@ -82,65 +528,320 @@ func Construct() {
 			}
 
 			// Refine the PR content using the same session for conversation continuity
-			refinement := &RefinementContext{
-				SessionID: sessionID,
-				Feedback:  feedback,
-			}
+			refinement := session.RefinementContext(feedback)
 
-			err = RunSpinnerWithTask("Refining PR content", func() error {
+			var result *PRGenerationResult
+			err := RunSpinnerWithTask("Refining PR content", func() error {
 				var err error
-				result, err = RefinePRContentWithProvider(config, diff, backgroundInfo, refinement)
+				result, err = RefinePRContentWithProvider(config, diff, session.Background, sections, refinement)
 				return err
 			})
 			if err != nil {
 				ShowError("Failed to refine PR content", err)
+				fmt.Println(infoStyle.Render("ℹ️  Run 'prgen resume' to continue from the last version"))
 				return
 			}
 
 			// Update with refined content (session ID should remain the same)
-			title, body, sessionID = result.Title, result.Body, result.SessionID
+			session.LLMSessionID = result.SessionID
+			session.AddIteration(feedback, result.Title, finalizeBody(run, session, result.Body))
+			session.LLMVersion = session.Version()
+			saveSession()
 
 			// Loop continues to show refined content
 			continue
+		case ChoiceTitles:
+			count := ResolveTitleCandidates(config, opts.Titles)
+			var titles *TitleCandidatesResult
+			err := RunSpinnerWithTask(fmt.Sprintf("Suggesting %d alternative titles", count), func() error {
+				var err error
+				titles, err = GenerateTitleCandidatesWithProvider(config, session.RefinementContext(""), count)
+				return err
+			})
+			if err != nil {
+				ShowError("Failed to suggest titles", err)
+				continue
+			}
+			// The provider's last response is now the title list, not a PR
+			session.LLMSessionID = titles.SessionID
+			session.LLMVersion = 0
+
+			var candidates []Candidate
+			for _, title := range titles.Titles {
+				candidates = append(candidates, Candidate{Title: title})
+			}
+			if index := AskCandidate("Pick a title", candidates, false); index >= 0 {
+				session.AddIteration("Picked alternative title", candidates[index].Title, current.Body)
+			}
+			saveSession()
+			continue
+		case ChoiceToggleState:
+			prOptions.State = prOptions.State.Next()
+			continue
+		case ChoiceUndo, ChoiceRedo, ChoiceSelectVersion:
+			version := session.Version()
+			switch choice {
+			case ChoiceUndo:
+				version--
+			case ChoiceRedo:
+				version++
+			default:
+				version = AskVersion(len(session.Iterations))
+			}
+			if err := session.SelectVersion(version); err != nil {
+				fmt.Println(warningStyle.Render("⚠️  " + err.Error()))
+				continue
+			}
+			saveSession()
+			continue
 		case ChoiceCancel:
 			fmt.Println(infoStyle.Render("ℹ️  PR creation cancelled by user"))
+			session.Status = SessionCancelled
+			saveSession()
 			return
 		}
 
 		// If we got here via ChoiceAccept, break the loop
 		break
 	}
+	ShowRunUsage()
 
-	// Push current branch to remote with spinner
-	err = RunSpinnerWithTask("Pushing current branch to remote", func() error {
-		return PushCurrentBranch()
-	})
-	if err != nil {
-		ShowError("Failed to push branch", err)
+	// Push current branch to remote, skipping or force pushing as needed
+	if !pushBranch(remotes.Push, branch, opts.NonInteractive) {
+		fmt.Println(infoStyle.Render("ℹ️  Run 'prgen resume' to try again with this content"))
 		return
 	}
 
 	// Create GitHub PR with spinner
+	current := session.Current()
 	var prURL string
-	err = RunSpinnerWithTask("Creating GitHub pull request", func() error {
-		var err error
-		prURL, err = CreateGitHubPR(title, body)
+	err := RunSpinnerWithTask("Creating GitHub pull request", func() error {
+		target, err := ResolvePRTarget(remotes, branch)
+		if err != nil {
+			return err
+		}
+		target.Base = run.base.Branch
+		prURL, err = CreateGitHubPR(current.Title, current.Body, target, prOptions)
 		return err
 	})
 	if err != nil {
 		ShowError("Failed to create GitHub PR", err)
-		return
+		if prURL == "" {
+			fmt.Println(infoStyle.Render("ℹ️  Run 'prgen resume' to try again with this content"))
+			return
+		}
+		// The PR exists but auto-merge could not be enabled
+		prOptions.State = PRStateReady
 	}
 
+	session.PRURL = prURL
+	session.Status = SessionCreated
+	saveSession()
+
 	// Show success with prominent URL display
-	ShowPRSuccess(prURL)
+	ShowPRSuccess(prURL, prOptions)
+
+	// Link the new PR from the other PRs of the stack
+	if len(session.Stack) > 0 {
+		for i := range session.Stack {
+			if session.Stack[i].Branch == branch {
+				session.Stack[i].PRURL = prURL
+			}
+		}
+		saveSession()
+
+		var updated int
+		err = RunSpinnerWithTask("Updating stacked PRs", func() error {
+			var err error
+			updated, err = UpdateStackPRs(session.Stack, run.base.Trunk, branch)
+			return err
+		})
+		if err != nil {
+			ShowError("Failed to update some stacked PRs", err)
+			// Don't return here - the PR was created
+		}
+		if updated > 0 {
+			fmt.Println(infoStyle.Render(fmt.Sprintf("🥞 Updated the stack section of %d PR(s), run 'prgen stack --update' to retry", updated)))
+		}
+	}
+
+	if config.GetBool("post_review_comments", false) && len(session.Findings) > 0 {
+		err = RunSpinnerWithTask("Posting review comments", func() error {
+			return PostReviewComments(prURL, session.Findings)
+		})
+		if err != nil {
+			ShowError("Failed to post review comments", err)
+			// Don't return here - the PR was created
+		}
+	}
+
+	if opts.NonInteractive {
+		return
+	}
 
 	// Open PR in browser with spinner
 	err = RunSpinnerWithTask("Opening PR in browser", func() error {
-		return OpenPRInBrowser()
+		return OpenPRInBrowser(prURL)
 	})
 	if err != nil {
 		ShowError("Failed to open PR in browser", err)
 		// Don't return here - this is not a critical error
 	}
 }
+
+// pushBranch pushes the current branch to remote after comparing it with the remote branch.
+// It returns false if the PR should not be created.
+func pushBranch(remote, branch string, nonInteractive bool) bool {
+	var status *PushStatus
+	err := RunSpinnerWithTask("Comparing with remote branch", func() error {
+		var err error
+		status, err = CheckPushStatus(remote, branch)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to check remote branch", err)
+		return false
+	}
+
+	switch status.State {
+	case PushUpToDate:
+		fmt.Println(infoStyle.Render(fmt.Sprintf("ℹ️  %s/%s is already up to date, skipping push", status.Remote, status.Branch)))
+		if err := SetUpstream(status.Remote, status.Branch); err != nil {
+			ShowError("Failed to set upstream branch", err)
+			// Don't return here - the PR can be created without upstream tracking
+		}
+		return true
+	case PushBehind, PushDiverged:
+		fmt.Println(warningStyle.Render("⚠️  " + status.Explain()))
+		if nonInteractive {
+			ShowError("Failed to push branch", fmt.Errorf("refusing to force push without confirmation"))
+			return false
+		}
+		if !AskConfirmationDefaultNo(fmt.Sprintf("Force push with --force-with-lease to %s/%s?", status.Remote, status.Branch)) {
+			fmt.Println(infoStyle.Render("ℹ️  Push cancelled, PR not created"))
+			return false
+		}
+		err = RunSpinnerWithTask("Force pushing current branch to remote", func() error {
+			return ForcePushBranch(status.Remote, status.Branch, status.RemoteSHA)
+		})
+	default:
+		err = RunSpinnerWithTask("Pushing current branch to remote", func() error {
+			return PushBranch(status.Remote, status.Branch)
+		})
+	}
+	if err != nil {
+		ShowError("Failed to push branch", err)
+		return false
+	}
+
+	return true
+}
+
+// handleUncommittedChanges warns about a dirty working tree and lets the user decide what to do.
+// It returns whether staged changes should be included in the diff, and false if prgen should stop.
+func handleUncommittedChanges(config *Config, nonInteractive bool) (includeStaged bool, ok bool) {
+	status, err := GetWorkingTreeStatus()
+	if err != nil {
+		ShowError("Failed to check working tree", err)
+		return false, false
+	}
+	if !status.IsDirty() {
+		return false, true
+	}
+
+	ShowWorkingTreeStatus(status)
+	if nonInteractive {
+		return false, true
+	}
+
+	switch AskDirtyTreeAction(status) {
+	case DirtyAbort:
+		fmt.Println(infoStyle.Render("ℹ️  PR creation cancelled by user"))
+		return false, false
+	case DirtyIncludeStaged:
+		fmt.Println(warningStyle.Render("⚠️  Staged changes are included in the description but will not be pushed until committed"))
+		return true, true
+	case DirtyCommit:
+		return false, commitWorkingTree(config, status)
+	default:
+		return false, true
+	}
+}
+
+// commitWorkingTree commits the tracked changes with a generated commit message.
+// Untracked files are left alone. It returns false if nothing was committed.
+func commitWorkingTree(config *Config, status *WorkingTreeStatus) bool {
+	// Keep the user's selection if they staged something, otherwise take all tracked changes.
+	// Nothing is staged until the message is confirmed, so cancelling leaves the index as it was.
+	stageAll := len(status.Staged) == 0
+	var diff string
+	var err error
+	if stageAll {
+		diff, err = GetTrackedChangesDiff()
+	} else {
+		diff, err = GetStagedDiff()
+	}
+	if err != nil {
+		ShowError("Failed to get the changes to commit", err)
+		return false
+	}
+	if diff == "" {
+		fmt.Println(warningStyle.Render("⚠️  No tracked changes to commit"))
+		return false
+	}
+
+	var result *CommitGenerationResult
+	err = RunSpinnerWithTask("Generating commit message", func() error {
+		var err error
+		result, err = GenerateCommitMessageWithProvider(config, diff)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to generate commit message", err)
+		return false
+	}
+	ShowCacheHits()
+	message := result.Message
+
+	fmt.Println(panelStyle.Render(message))
+	if !AskConfirmation("Commit with this message?") {
+		fmt.Println(infoStyle.Render("ℹ️  Commit cancelled, PR creation stopped"))
+		return false
+	}
+
+	err = RunSpinnerWithTask("Committing changes", func() error {
+		if stageAll {
+			if err := StageTrackedChanges(); err != nil {
+				return err
+			}
+		}
+		if err := CommitStaged(message); err != nil {
+			// The index was empty before, so unstaging everything restores it
+			if stageAll {
+				if resetErr := ResetIndex(); resetErr != nil {
+					return fmt.Errorf("%w; %v", err, resetErr)
+				}
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		ShowError("Failed to commit changes", err)
+		return false
+	}
+
+	if len(status.Untracked) > 0 {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("ℹ️  %d untracked file(s) were not committed", len(status.Untracked))))
+	}
+	return true
+}
+
+// findExistingPR returns the URL of an open PR for branch, or "" if there is none
+func findExistingPR(remotes Remotes, branch string) (string, error) {
+	target, err := ResolvePRTarget(remotes, branch)
+	if err != nil {
+		return "", err
+	}
+	return FindOpenPR(target)
+}
@@ -0,0 +1,580 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxDependencyChanges bounds the dependency changes listed in the prompt and body
+const DefaultMaxDependencyChanges = 40
+
+// dependencySectionHeading is the heading of the dependency table appended to PR bodies
+const dependencySectionHeading = "## Dependencies"
+
+// Dependency change kinds
+const (
+	DependencyAdded      = "added"
+	DependencyRemoved    = "removed"
+	DependencyUpgraded   = "upgraded"
+	DependencyDowngraded = "downgraded"
+	DependencyChanged    = "changed" // Several versions changed at once
+)
+
+// DependencyChange is a package whose version changed in a manifest or lockfile
+type DependencyChange struct {
+	File       string `json:"file"`
+	Ecosystem  string `json:"ecosystem"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	OldVersion string `json:"old_version,omitempty"`
+	NewVersion string `json:"new_version,omitempty"`
+	Major      bool   `json:"major,omitempty"` // Major version bump, or minor bump of a 0.x version
+}
+
+// dependencyParser reads package versions from one kind of manifest or lockfile.
+// A nil parse function means the file is summarized by another file, e.g. go.sum by go.mod.
+type dependencyParser struct {
+	ecosystem string
+	lockfile  bool // Lockfile diffs are replaced by the summary; manifests are small enough to keep
+	parse     func(content []byte) (map[string][]string, error)
+}
+
+// dependencyParsers maps dependency file names to their parsers
+var dependencyParsers = map[string]dependencyParser{
+	"go.mod":            {"go", false, parseGoMod},
+	"go.sum":            {"go", true, nil},
+	"package-lock.json": {"npm", true, parsePackageLock},
+	"yarn.lock":         {"npm", true, parseYarnLock},
+	"Cargo.lock":        {"cargo", true, parseTOMLPackages},
+	"poetry.lock":       {"python", true, parseTOMLPackages},
+}
+
+// isLockfile reports whether path is a lockfile whose diff is replaced by the dependency summary
+func isLockfile(filePath string) bool {
+	parser, ok := dependencyParsers[path.Base(filePath)]
+	return ok && parser.lockfile
+}
+
+// SummarizeDependencies parses the dependency files changed in the diff and returns their version changes.
+// Files whose versions cannot be read or parsed are skipped.
+func SummarizeDependencies(diff string) []DependencyChange {
+	files, err := parseDiffByFile(diff)
+	if err != nil {
+		return nil
+	}
+
+	var changes []DependencyChange
+	for _, file := range files {
+		parser, ok := dependencyParsers[path.Base(file.Path)]
+		if !ok || parser.parse == nil {
+			continue
+		}
+
+		before, err := readBlob(file.OldBlob)
+		if err != nil {
+			continue
+		}
+		after, err := readBlob(file.NewBlob)
+		if err != nil {
+			if after, err = readWorkingTreeFile(file.Path); err != nil {
+				continue
+			}
+		}
+
+		oldVersions, err := parseDependencyVersions(parser, before)
+		if err != nil {
+			continue
+		}
+		newVersions, err := parseDependencyVersions(parser, after)
+		if err != nil {
+			continue
+		}
+		changes = append(changes, compareDependencies(file.Path, parser.ecosystem, oldVersions, newVersions)...)
+	}
+
+	sortDependencyChanges(changes)
+	return changes
+}
+
+// parseDependencyVersions parses a file version, treating a missing side as having no dependencies
+func parseDependencyVersions(parser dependencyParser, content []byte) (map[string][]string, error) {
+	if content == nil {
+		return map[string][]string{}, nil
+	}
+	return parser.parse(content)
+}
+
+// compareDependencies lists the packages whose set of versions differs between the two versions of a file
+func compareDependencies(file, ecosystem string, oldVersions, newVersions map[string][]string) []DependencyChange {
+	names := make(map[string]bool)
+	for name := range oldVersions {
+		names[name] = true
+	}
+	for name := range newVersions {
+		names[name] = true
+	}
+
+	var changes []DependencyChange
+	for name := range names {
+		removed, added := versionSetDiff(oldVersions[name], newVersions[name])
+		if len(removed) == 0 && len(added) == 0 {
+			continue
+		}
+
+		change := DependencyChange{
+			File:       file,
+			Ecosystem:  ecosystem,
+			Name:       name,
+			OldVersion: strings.Join(removed, ", "),
+			NewVersion: strings.Join(added, ", "),
+		}
+		switch {
+		case len(oldVersions[name]) == 0:
+			change.Kind = DependencyAdded
+		case len(newVersions[name]) == 0:
+			change.Kind = DependencyRemoved
+		case len(removed) == 1 && len(added) == 1 && (strings.HasPrefix(removed[0], goReplacePrefix) || strings.HasPrefix(added[0], goReplacePrefix)):
+			change.Kind = DependencyChanged
+		case len(removed) == 1 && len(added) == 1:
+			change.Kind = DependencyUpgraded
+			if compareVersions(removed[0], added[0]) > 0 {
+				change.Kind = DependencyDowngraded
+			}
+			change.Major = isMajorBump(removed[0], added[0])
+		default:
+			change.Kind = DependencyChanged
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// versionSetDiff returns the versions only in old and only in new
+func versionSetDiff(oldSet, newSet []string) (removed, added []string) {
+	inOld := make(map[string]bool, len(oldSet))
+	for _, version := range oldSet {
+		inOld[version] = true
+	}
+	inNew := make(map[string]bool, len(newSet))
+	for _, version := range newSet {
+		inNew[version] = true
+	}
+
+	for _, version := range oldSet {
+		if !inNew[version] {
+			removed = append(removed, version)
+		}
+	}
+	for _, version := range newSet {
+		if !inOld[version] {
+			added = append(added, version)
+		}
+	}
+	return removed, added
+}
+
+// sortDependencyChanges puts major bumps first, then additions and removals, then the rest by name
+func sortDependencyChanges(changes []DependencyChange) {
+	rank := func(change DependencyChange) int {
+		switch {
+		case change.Major:
+			return 0
+		case change.Kind == DependencyAdded || change.Kind == DependencyRemoved:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if rank(changes[i]) != rank(changes[j]) {
+			return rank(changes[i]) < rank(changes[j])
+		}
+		if changes[i].File != changes[j].File {
+			return changes[i].File < changes[j].File
+		}
+		return changes[i].Name < changes[j].Name
+	})
+}
+
+var versionNumberPattern = regexp.MustCompile(`\d+`)
+
+// versionNumbers returns the leading major, minor and patch numbers of a version such as v1.2.3-rc.1
+func versionNumbers(version string) []int {
+	core, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), "-")
+	core, _, _ = strings.Cut(core, "+")
+
+	var numbers []int
+	for _, match := range versionNumberPattern.FindAllString(core, 3) {
+		n, _ := strconv.Atoi(match)
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// compareVersions compares two versions numerically, falling back to string order
+func compareVersions(a, b string) int {
+	na, nb := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(na) && i < len(nb); i++ {
+		if na[i] != nb[i] {
+			if na[i] < nb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	if len(na) != len(nb) || len(na) == 0 {
+		return strings.Compare(a, b)
+	}
+	return 0
+}
+
+// isMajorBump reports a breaking version change under semver: a new major version,
+// or a new minor version while the major version is 0
+func isMajorBump(oldVersion, newVersion string) bool {
+	oldNumbers, newNumbers := versionNumbers(oldVersion), versionNumbers(newVersion)
+	if len(oldNumbers) == 0 || len(newNumbers) == 0 {
+		return false
+	}
+	if oldNumbers[0] != newNumbers[0] {
+		return true
+	}
+	return oldNumbers[0] == 0 && len(oldNumbers) > 1 && len(newNumbers) > 1 && oldNumbers[1] != newNumbers[1]
+}
+
+// parseGoMod reads the require directives of a go.mod file, with replace directives applied
+// so that the versions are the ones actually built
+func parseGoMod(content []byte) (map[string][]string, error) {
+	versions := make(map[string][]string)
+	var replaces [][]string
+	block := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if comment := strings.Index(line, "//"); comment != -1 {
+			line = strings.TrimSpace(line[:comment])
+		}
+
+		directive := block
+		switch {
+		case line == "require (" || line == "replace (":
+			block = strings.TrimSuffix(line, " (")
+			continue
+		case block != "" && line == ")":
+			block = ""
+			continue
+		case strings.HasPrefix(line, "require ") || strings.HasPrefix(line, "replace "):
+			directive, line, _ = strings.Cut(line, " ")
+		case block == "":
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case directive == "require" && len(fields) == 2:
+			versions[fields[0]] = append(versions[fields[0]], fields[1])
+		case directive == "replace" && len(fields) >= 3:
+			replaces = append(replaces, fields)
+		}
+	}
+
+	for _, fields := range replaces {
+		applyGoModReplace(versions, fields)
+	}
+	return versions, scanner.Err()
+}
+
+// goReplacePrefix marks a go.mod version that was replaced by another module or a local path
+const goReplacePrefix = "replaced by "
+
+// applyGoModReplace applies a replace directive such as "old v1.0.0 => new v1.1.0" or "old => ../local".
+// The replacement is recorded as the module's version, so changing it shows as a version change.
+func applyGoModReplace(versions map[string][]string, fields []string) {
+	arrow := -1
+	for i, field := range fields {
+		if field == "=>" {
+			arrow = i
+		}
+	}
+	if arrow < 1 || arrow == len(fields)-1 {
+		return
+	}
+
+	// A replacement by the same module at another version is an ordinary version; other
+	// modules and local paths are marked so they are not compared as versions
+	module, target := fields[0], strings.Join(fields[arrow+1:], " ")
+	if strings.HasPrefix(target, module+" ") {
+		target = strings.TrimPrefix(target, module+" ")
+	} else {
+		target = goReplacePrefix + target
+	}
+	// A replace without a version applies to every required version
+	oldVersion := ""
+	if arrow == 2 {
+		oldVersion = fields[1]
+	}
+
+	required, ok := versions[module]
+	if !ok {
+		return
+	}
+	for i, version := range required {
+		if oldVersion == "" || version == oldVersion {
+			required[i] = target
+		}
+	}
+}
+
+// parsePackageLock reads package versions from package-lock.json (lockfile version 1, 2 or 3)
+func parsePackageLock(content []byte) (map[string][]string, error) {
+	var lock struct {
+		Packages map[string]struct {
+			Version string `json:"version"`
+		} `json:"packages"`
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse package-lock.json: %w", err)
+	}
+
+	versions := make(map[string][]string)
+	if len(lock.Packages) > 0 {
+		for key, pkg := range lock.Packages {
+			idx := strings.LastIndex(key, "node_modules/")
+			if key == "" || idx == -1 || pkg.Version == "" {
+				// The root project and workspace links have no installed version
+				continue
+			}
+			name := key[idx+len("node_modules/"):]
+			versions[name] = appendUnique(versions[name], pkg.Version)
+		}
+		return versions, nil
+	}
+
+	for name, dep := range lock.Dependencies {
+		versions[name] = appendUnique(versions[name], dep.Version)
+	}
+	return versions, nil
+}
+
+// parseYarnLock reads package versions from a classic or Berry yarn.lock
+func parseYarnLock(content []byte) (map[string][]string, error) {
+	versions := make(map[string][]string)
+	var current string
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// Entry headers are unindented, e.g. `"@babel/core@^7.0.0", "@babel/core@^7.1.0":`
+		if !strings.HasPrefix(line, " ") && strings.HasSuffix(trimmed, ":") {
+			spec := strings.TrimSpace(strings.Split(strings.TrimSuffix(trimmed, ":"), ",")[0])
+			current = yarnPackageName(strings.Trim(spec, `"`))
+			if current == "__metadata" {
+				// Berry's metadata entry has a version of its own
+				current = ""
+			}
+			continue
+		}
+
+		if current != "" && (strings.HasPrefix(trimmed, "version ") || strings.HasPrefix(trimmed, "version:")) {
+			version := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(trimmed, "version"), ":"))
+			versions[current] = appendUnique(versions[current], strings.Trim(version, `"`))
+			current = ""
+		}
+	}
+	return versions, scanner.Err()
+}
+
+// yarnPackageName strips the range from a yarn.lock spec such as @scope/name@npm:^1.0.0
+func yarnPackageName(spec string) string {
+	if idx := strings.LastIndex(spec, "@"); idx > 0 {
+		return spec[:idx]
+	}
+	return spec
+}
+
+// parseTOMLPackages reads the [[package]] name and version pairs of Cargo.lock and poetry.lock
+func parseTOMLPackages(content []byte) (map[string][]string, error) {
+	versions := make(map[string][]string)
+	var name, version string
+	inPackage := false
+
+	flush := func() {
+		if inPackage && name != "" && version != "" {
+			versions[name] = appendUnique(versions[name], version)
+		}
+		name, version = "", ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			flush()
+			inPackage = line == "[[package]]"
+			continue
+		}
+		if !inPackage {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.TrimSpace(key) {
+		case "name":
+			name = value
+		case "version":
+			version = value
+		}
+	}
+	flush()
+	return versions, scanner.Err()
+}
+
+// appendUnique appends value unless it is already present
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// describeDependencyChange returns e.g. "upgraded github.com/foo/bar v1.2.0 -> v2.0.0 (major)"
+func describeDependencyChange(change DependencyChange) string {
+	text := change.Kind + " " + change.Name
+	switch change.Kind {
+	case DependencyAdded:
+		text += " " + change.NewVersion
+	case DependencyRemoved:
+		text += " " + change.OldVersion
+	default:
+		text += " " + change.OldVersion + " -> " + change.NewVersion
+	}
+	if change.Major {
+		text += " (major)"
+	}
+	return text
+}
+
+// BuildDependencyPromptSection formats the dependency changes for the prompt, grouped by file
+func BuildDependencyPromptSection(changes []DependencyChange, maxChanges int) string {
+	var result strings.Builder
+
+	shown := changes
+	if maxChanges > 0 && len(shown) > maxChanges {
+		shown = shown[:maxChanges]
+	}
+
+	byFile := make(map[string][]DependencyChange)
+	var files []string
+	for _, change := range shown {
+		if _, ok := byFile[change.File]; !ok {
+			files = append(files, change.File)
+		}
+		byFile[change.File] = append(byFile[change.File], change)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		result.WriteString(fmt.Sprintf("%s (%s):\n", file, byFile[file][0].Ecosystem))
+		for _, change := range byFile[file] {
+			result.WriteString("- " + describeDependencyChange(change) + "\n")
+		}
+	}
+	if len(changes) > len(shown) {
+		result.WriteString(fmt.Sprintf("... and %d more dependency changes\n", len(changes)-len(shown)))
+	}
+	return strings.TrimSpace(result.String())
+}
+
+// StripLockfileDiffs replaces the diffs of lockfiles with a note pointing to the dependency summary.
+// Lockfiles that produced no parsed changes are kept, since nothing else describes them.
+func StripLockfileDiffs(diff string, changes []DependencyChange) string {
+	files, err := parseDiffByFile(diff)
+	if err != nil || len(files) == 0 {
+		return diff
+	}
+
+	summarized := make(map[string]bool)
+	for _, change := range changes {
+		summarized[change.File] = true
+	}
+
+	var result strings.Builder
+	for _, file := range files {
+		if !isLockfile(file.Path) || !summarized[summarizingFile(file.Path)] {
+			result.WriteString(file.Content)
+			continue
+		}
+		result.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", file.Path, file.Path))
+		result.WriteString(fmt.Sprintf("# Lockfile: +%d -%d lines (version changes are listed under DEPENDENCIES)\n",
+			file.LinesAdded, file.LinesRemoved))
+	}
+	return strings.TrimSpace(result.String())
+}
+
+// summarizingFile returns the file whose parsed changes describe filePath, e.g. go.mod for go.sum
+func summarizingFile(filePath string) string {
+	if path.Base(filePath) == "go.sum" {
+		return path.Join(path.Dir(filePath), "go.mod")
+	}
+	return filePath
+}
+
+// AppendDependencySection adds a dependency table to the PR body unless it is already there
+func AppendDependencySection(body string, changes []DependencyChange, maxChanges int) string {
+	if len(changes) == 0 || strings.Contains(body, dependencySectionHeading) {
+		return body
+	}
+
+	shown := changes
+	if maxChanges > 0 && len(shown) > maxChanges {
+		shown = shown[:maxChanges]
+	}
+
+	var section strings.Builder
+	section.WriteString(dependencySectionHeading + "\n")
+	section.WriteString("| Package | Change | Version |\n")
+	section.WriteString("| --- | --- | --- |\n")
+	for _, change := range shown {
+		kind := change.Kind
+		if change.Major {
+			kind = "⚠️ major " + kind
+		}
+		var version string
+		switch change.Kind {
+		case DependencyAdded:
+			version = change.NewVersion
+		case DependencyRemoved:
+			version = change.OldVersion
+		default:
+			version = change.OldVersion + " → " + change.NewVersion
+		}
+		section.WriteString(fmt.Sprintf("| `%s` | %s | %s |\n", change.Name, kind, version))
+	}
+	if len(changes) > len(shown) {
+		section.WriteString(fmt.Sprintf("\n_...and %d more dependency changes._\n", len(changes)-len(shown)))
+	}
+
+	return strings.TrimRight(body, "\n") + "\n\n" + strings.TrimRight(section.String(), "\n")
+}
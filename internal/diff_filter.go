@@ -2,7 +2,7 @@ package internal
 
 import (
 	"fmt"
-	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -11,21 +11,12 @@ const (
 	MaxLinesPerFile = 1000
 	// MaxTotalTokens is our target after filtering
 	MaxTotalTokens = 6000 // Leave some buffer under the 8000 limit
-	// SummaryLines is how many lines to show from large files
+	// SummaryLines is half the number of lines shown from a single large hunk
 	SummaryLines = 50
+	// minHunkLines is the fewest lines of a file's most significant hunk shown when the budget is tight
+	minHunkLines = 6
 )
 
-// FileChange represents changes to a single file
-type FileChange struct {
-	Path         string
-	IsNew        bool
-	IsDeleted    bool
-	LinesAdded   int
-	LinesRemoved int
-	Content      string
-	IsGenerated  bool
-}
-
 // DiffSummary contains the filtered and summarized diff
 type DiffSummary struct {
 	Files        []FileChange
@@ -34,84 +25,37 @@ type DiffSummary struct {
 }
 
 // FilterDiff processes a git diff and filters out or summarizes large/generated files
-func FilterDiff(diff string) (*DiffSummary, error) {
-	files, err := parseDiffByFile(diff)
+// Files are included in order of relevance, see ScoreFiles
+func FilterDiff(config *Config, diff string) (*DiffSummary, error) {
+	files, err := parseChangedFiles(diff)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse diff: %w", err)
-	}
-
-	// Mark auto-generated files
-	for i := range files {
-		files[i].IsGenerated = isAutoGenerated(files[i].Path, files[i].Content)
+		return nil, err
 	}
 
 	// Filter and summarize
 	summary := &DiffSummary{Files: files}
-	summary.FilteredDiff = buildFilteredDiff(files)
+	contextLines := DefaultDiffContextLines
+	if config != nil {
+		contextLines = config.GetInt("diff_context_lines", DefaultDiffContextLines)
+	}
+	summary.FilteredDiff = buildFilteredDiff(rankFiles(config, files), contextLines)
 
 	return summary, nil
 }
 
-// parseDiffByFile splits a git diff into per-file changes
-func parseDiffByFile(diff string) ([]FileChange, error) {
-	var files []FileChange
-
-	// Split diff by file headers (lines starting with "diff --git")
-	diffPattern := regexp.MustCompile(`(?m)^diff --git a/(.+?) b/(.+?)$`)
-	fileStartIndices := diffPattern.FindAllStringIndex(diff, -1)
-	matches := diffPattern.FindAllStringSubmatch(diff, -1)
-
-	if len(fileStartIndices) == 0 {
-		// No file headers found, treat as single change
-		return []FileChange{{
-			Path:    "unknown",
-			Content: diff,
-		}}, nil
+// parseChangedFiles splits a diff into files and marks the auto-generated ones
+func parseChangedFiles(diff string) ([]FileChange, error) {
+	files, err := parseDiffByFile(diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
 	}
 
-	for i, match := range matches {
-		start := fileStartIndices[i][0]
-		var end int
-		if i+1 < len(fileStartIndices) {
-			end = fileStartIndices[i+1][0]
-		} else {
-			end = len(diff)
-		}
-
-		filePath := match[2] // Use the "b/" path (destination)
-		fileContent := diff[start:end]
-
-		file := FileChange{
-			Path:    filePath,
-			Content: fileContent,
-		}
-
-		// Analyze the file change
-		analyzeFileChange(&file)
-
-		files = append(files, file)
+	for i := range files {
+		files[i].IsGenerated = isAutoGenerated(files[i].Path, files[i].Content)
 	}
-
 	return files, nil
 }
 
-// analyzeFileChange extracts metadata about a file change
-func analyzeFileChange(file *FileChange) {
-	lines := strings.Split(file.Content, "\n")
-
-	for _, line := range lines {
-		if strings.HasPrefix(line, "new file mode") {
-			file.IsNew = true
-		} else if strings.HasPrefix(line, "deleted file mode") {
-			file.IsDeleted = true
-		} else if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-			file.LinesAdded++
-		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
-			file.LinesRemoved++
-		}
-	}
-}
-
 // isAutoGenerated detects if a file is likely auto-generated
 func isAutoGenerated(path, content string) bool {
 	// Check file extension patterns
@@ -158,127 +102,156 @@ func isAutoGenerated(path, content string) bool {
 	return false
 }
 
-// buildFilteredDiff creates a filtered version of the diff
-func buildFilteredDiff(files []FileChange) string {
-	var result strings.Builder
-	currentTokens := 0
+// budgetedFile is a file's share of the filtered diff
+type budgetedFile struct {
+	file     FileChange
+	text     string // Replaces the hunks: a summary, or the content of a file without hunks
+	header   []string
+	hunks    []diffHunk
+	included []bool
+	limits   []int // Number of lines shown of each included hunk
+}
 
-	// Sort files by priority (non-generated first, smaller changes first)
-	prioritizedFiles := prioritizeFiles(files)
+// buildFilteredDiff creates a filtered version of the diff from files in priority order.
+// The token budget is shared at hunk granularity: every file first gets its most
+// significant hunk, then the remaining budget goes to further hunks of the most relevant files.
+func buildFilteredDiff(files []FileChange, contextLines int) string {
+	budget := MaxTotalTokens
+	var budgeted []*budgetedFile
 
-	for _, file := range prioritizedFiles {
-		fileTokens := estimateTokens(file.Content)
+	for _, file := range files {
+		entry := &budgetedFile{file: file}
+		header, hunks := splitHunks(file.Content)
 
-		if file.IsGenerated {
+		switch {
+		case file.IsGenerated:
 			// For generated files, add a summary instead of full content
-			summary := fmt.Sprintf("diff --git a/%s b/%s\n", file.Path, file.Path)
-			if file.IsNew {
-				summary += "new file mode 100644\n"
-			} else if file.IsDeleted {
-				summary += "deleted file mode 100644\n"
+			entry.text = fmt.Sprintf("diff --git a/%s b/%s\n", file.OldPath, file.Path) +
+				fmt.Sprintf("# Auto-generated file, %s (content truncated)\n", file.Describe())
+		case len(hunks) == 0:
+			// Binary files, pure renames and mode changes are only headers
+			entry.text = file.Content
+		case file.LinesAdded+file.LinesRemoved > MaxLinesPerFile:
+			// For large files, prefer a structured summary over a selection of hunks
+			entry.text = summarizeFileChange(file)
+		}
+
+		if entry.text == "" {
+			entry.header = header
+			for _, hunk := range hunks {
+				for _, part := range trimContext(hunk, contextLines) {
+					classifyHunk(&part, file.Path)
+					entry.hunks = append(entry.hunks, part)
+				}
 			}
-			summary += fmt.Sprintf("# Auto-generated file: +%d -%d lines (content truncated)\n",
-				file.LinesAdded, file.LinesRemoved)
+			entry.included = make([]bool, len(entry.hunks))
+			entry.limits = make([]int, len(entry.hunks))
+		}
 
-			result.WriteString(summary)
-			currentTokens += estimateTokens(summary)
-		} else if file.LinesAdded+file.LinesRemoved > MaxLinesPerFile {
-			// For large non-generated files, show truncated version
-			truncated := truncateFileContent(file)
-			result.WriteString(truncated)
-			currentTokens += estimateTokens(truncated)
-		} else if currentTokens+fileTokens < MaxTotalTokens {
-			// Include full content for normal files
-			result.WriteString(file.Content)
-			currentTokens += fileTokens
-		} else {
+		cost := estimateTokens(entry.render())
+		if cost > budget {
 			// Token limit reached, add summary for remaining files
-			summary := fmt.Sprintf("# Additional file: %s (+%d -%d lines, truncated due to size)\n",
-				file.Path, file.LinesAdded, file.LinesRemoved)
-			result.WriteString(summary)
+			entry = &budgetedFile{file: file, text: fmt.Sprintf("# Additional file, %s (truncated due to size)\n", file.Describe())}
+			cost = estimateTokens(entry.text)
 		}
+		budget -= cost
+		budgeted = append(budgeted, entry)
+	}
 
-		result.WriteString("\n")
-
-		// Stop if we're approaching token limit
-		if currentTokens > MaxTotalTokens {
-			break
+	// Every file gets its most significant hunk first, cut down to a fair share of the
+	// budget when needed, then the most relevant files get the rest
+	var pending []*budgetedFile
+	for _, entry := range budgeted {
+		if len(entry.rankedHunks()) > 0 {
+			pending = append(pending, entry)
+		}
+	}
+	for i, entry := range pending {
+		share := budget / (len(pending) - i)
+		budget -= entry.include(entry.rankedHunks()[0], share, minHunkLines)
+	}
+	for _, entry := range budgeted {
+		for _, index := range entry.rankedHunks() {
+			budget -= entry.include(index, budget, SummaryLines*2)
 		}
 	}
 
+	var result strings.Builder
+	for _, entry := range budgeted {
+		result.WriteString(entry.render())
+		result.WriteString("\n")
+	}
 	return result.String()
 }
 
-// prioritizeFiles sorts files by importance for PR description
-func prioritizeFiles(files []FileChange) []FileChange {
-	// Create a copy to avoid modifying the original
-	sorted := make([]FileChange, len(files))
-	copy(sorted, files)
-
-	// Simple priority: non-generated first, then by size
-	for i := 0; i < len(sorted)-1; i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if shouldSwap(sorted[i], sorted[j]) {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
+// rankedHunks returns the indexes of the code hunks not included yet, most significant first
+func (b *budgetedFile) rankedHunks() []int {
+	var indexes []int
+	for i, hunk := range b.hunks {
+		if hunk.Kind == HunkCode && !b.included[i] {
+			indexes = append(indexes, i)
 		}
 	}
-
-	return sorted
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return b.hunks[indexes[i]].Significance > b.hunks[indexes[j]].Significance
+	})
+	return indexes
 }
 
-// shouldSwap determines if two files should be swapped in priority order
-func shouldSwap(a, b FileChange) bool {
-	// Non-generated files have higher priority
-	if !a.IsGenerated && b.IsGenerated {
-		return false
-	}
-	if a.IsGenerated && !b.IsGenerated {
-		return true
+// include adds a hunk, cut to as few as minLines lines to fit the budget, and returns the tokens it used
+func (b *budgetedFile) include(index, budget, minLines int) int {
+	for limit := SummaryLines * 2; limit >= minLines; limit /= 2 {
+		if cost := estimateTokens(b.hunks[index].RenderTruncated(limit)); cost <= budget {
+			b.included[index] = true
+			b.limits[index] = limit
+			return cost
+		}
 	}
-
-	// Smaller files have higher priority
-	aSize := a.LinesAdded + a.LinesRemoved
-	bSize := b.LinesAdded + b.LinesRemoved
-	return aSize > bSize
+	return 0
 }
 
-// truncateFileContent shows a summary of a large file change
-func truncateFileContent(file FileChange) string {
-	lines := strings.Split(file.Content, "\n")
-
-	if len(lines) <= SummaryLines*2 {
-		return file.Content
+// render returns the file's part of the filtered diff; hunks left out are replaced by a note
+func (b *budgetedFile) render() string {
+	if b.text != "" {
+		return b.text
 	}
 
 	var result strings.Builder
-
-	// Show file header
-	headerLines := 0
-	for _, line := range lines {
+	for _, line := range b.header {
 		result.WriteString(line + "\n")
-		headerLines++
+	}
 
-		if strings.HasPrefix(line, "@@") || headerLines >= 10 {
-			break
+	omitted, omittedAdded, omittedRemoved := 0, 0, 0
+	flushOmitted := func() {
+		if omitted > 0 {
+			result.WriteString(fmt.Sprintf("# ... %d hunk(s) omitted: +%d -%d lines ...\n", omitted, omittedAdded, omittedRemoved))
+			omitted, omittedAdded, omittedRemoved = 0, 0, 0
 		}
 	}
 
-	// Show first few changes
-	changeLines := 0
-	for lineIdx := headerLines; lineIdx < len(lines) && changeLines < SummaryLines; lineIdx++ {
-		line := lines[lineIdx]
-		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
-			result.WriteString(line + "\n")
-			changeLines++
-		} else if changeLines > 0 {
-			result.WriteString(line + "\n")
+	for i, hunk := range b.hunks {
+		switch {
+		case hunk.Kind != HunkCode:
+			flushOmitted()
+			result.WriteString(fmt.Sprintf("%s\n# %s change collapsed: +%d -%d lines\n", hunk.Header(), hunk.Kind, hunk.Added, hunk.Removed))
+		case b.included[i]:
+			flushOmitted()
+			result.WriteString(hunk.RenderTruncated(b.limits[i]))
+		default:
+			omitted++
+			omittedAdded += hunk.Added
+			omittedRemoved += hunk.Removed
 		}
 	}
-
-	// Add truncation notice
-	result.WriteString(fmt.Sprintf("\n# ... (truncated: %d total lines changed) ...\n",
-		file.LinesAdded+file.LinesRemoved))
-
+	flushOmitted()
 	return result.String()
 }
+
+// rankFiles returns the files ordered by relevance score, most relevant first
+func rankFiles(config *Config, files []FileChange) []FileChange {
+	ranked := make([]FileChange, 0, len(files))
+	for _, score := range ScoreFiles(config, files) {
+		ranked = append(ranked, score.File)
+	}
+	return ranked
+}
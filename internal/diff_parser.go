@@ -0,0 +1,345 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ChangeStatus is the kind of change git reports for a file
+type ChangeStatus string
+
+const (
+	StatusModified ChangeStatus = "modified"
+	StatusAdded    ChangeStatus = "added"
+	StatusDeleted  ChangeStatus = "deleted"
+	StatusRenamed  ChangeStatus = "renamed"
+	StatusCopied   ChangeStatus = "copied"
+)
+
+// unknownDiffPath names a change whose path could not be read from the diff
+const unknownDiffPath = "unknown"
+
+// File modes with a special meaning in git
+const (
+	modeSymlink    = "120000"
+	modeSubmodule  = "160000"
+	modeExecutable = "100755"
+)
+
+// FileChange represents changes to a single file
+type FileChange struct {
+	Path         string // Path after the change; the old path for deleted files
+	OldPath      string // Path before the change, differs from Path for renames and copies
+	Status       ChangeStatus
+	IsNew        bool
+	IsDeleted    bool
+	IsBinary     bool
+	Similarity   int    // Similarity index in percent for renames and copies
+	OldMode      string // File modes, e.g. 100644; empty when unchanged and not reported
+	NewMode      string
+	LinesAdded   int
+	LinesRemoved int
+	Content      string
+	IsGenerated  bool
+	OldBlob      string // Blob hashes from the "index" line, all zeros for a missing side
+	NewBlob      string
+}
+
+// IsSymlink reports whether the file is a symbolic link on either side of the change
+func (f FileChange) IsSymlink() bool {
+	return f.OldMode == modeSymlink || f.NewMode == modeSymlink
+}
+
+// IsSubmodule reports whether the change is a submodule commit update
+func (f FileChange) IsSubmodule() bool {
+	return f.OldMode == modeSubmodule || f.NewMode == modeSubmodule
+}
+
+// ModeChanged reports whether the file mode changed, e.g. it became executable
+func (f FileChange) ModeChanged() bool {
+	return f.OldMode != "" && f.NewMode != "" && f.OldMode != f.NewMode
+}
+
+// IsNotable reports whether the change is more than a plain content edit of a regular file
+func (f FileChange) IsNotable() bool {
+	return f.Status == StatusRenamed || f.Status == StatusCopied || f.IsBinary ||
+		f.IsSymlink() || f.IsSubmodule() || f.ModeChanged()
+}
+
+// Describe returns a one-line description such as
+// "renamed: old.go -> new.go (95% similar), +3 -1 lines"
+func (f FileChange) Describe() string {
+	var text string
+	switch f.Status {
+	case StatusRenamed, StatusCopied:
+		text = fmt.Sprintf("%s: %s -> %s", f.Status, f.OldPath, f.Path)
+		if f.Similarity > 0 {
+			text += fmt.Sprintf(" (%d%% similar)", f.Similarity)
+		}
+	default:
+		text = fmt.Sprintf("%s: %s", f.Status, f.Path)
+	}
+
+	var notes []string
+	switch {
+	case f.IsSubmodule():
+		notes = append(notes, "submodule")
+	case f.IsSymlink():
+		notes = append(notes, "symlink")
+	}
+	if f.IsBinary {
+		notes = append(notes, "binary")
+	}
+	if f.ModeChanged() {
+		note := fmt.Sprintf("mode %s -> %s", f.OldMode, f.NewMode)
+		if f.NewMode == modeExecutable {
+			note += " (now executable)"
+		} else if f.OldMode == modeExecutable {
+			note += " (no longer executable)"
+		}
+		notes = append(notes, note)
+	}
+	if !f.IsBinary && f.LinesAdded+f.LinesRemoved > 0 {
+		notes = append(notes, fmt.Sprintf("+%d -%d lines", f.LinesAdded, f.LinesRemoved))
+	}
+
+	if len(notes) > 0 {
+		text += ", " + strings.Join(notes, ", ")
+	}
+	return text
+}
+
+// BuildFileOperationsSection describes renames, copies, binary files, symlinks and mode changes,
+// which a truncated or summarized diff would otherwise hide. It returns "" if there are none.
+func BuildFileOperationsSection(diff string) string {
+	files, err := parseDiffByFile(diff)
+	if err != nil {
+		return ""
+	}
+
+	var lines []string
+	for _, file := range files {
+		if file.IsNotable() {
+			lines = append(lines, "- "+file.Describe())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseDiffByFile splits a git diff into per-file changes and parses their extended headers
+func parseDiffByFile(diff string) ([]FileChange, error) {
+	lines := strings.SplitAfter(diff, "\n")
+
+	var starts []int
+	for i, line := range lines {
+		if strings.HasPrefix(line, "diff --git ") {
+			starts = append(starts, i)
+		}
+	}
+
+	// Without file headers (e.g. a diff produced outside GetDiff) the diff is treated as a single change
+	if len(starts) == 0 {
+		return []FileChange{{
+			Path:    unknownDiffPath,
+			Status:  StatusModified,
+			Content: diff,
+		}}, nil
+	}
+
+	var files []FileChange
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+
+		// A header in a format we do not know only affects its own file, which keeps
+		// its raw content and the paths of its "---"/"+++" lines
+		file, _ := parseFileChange(lines[start:end])
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// parseFileChange parses the lines of one file's diff, starting with its "diff --git" line.
+// When that line cannot be parsed, the rest of the headers are still read and the error is returned
+// with the file; its path is then taken from the other headers, or is unknownDiffPath.
+func parseFileChange(lines []string) (FileChange, error) {
+	file := FileChange{
+		Status:  StatusModified,
+		Content: strings.Join(lines, ""),
+	}
+
+	oldPath, newPath, err := parseDiffGitLine(strings.TrimRight(lines[0], "\n"))
+
+	inHunk := false
+	for _, raw := range lines[1:] {
+		line := strings.TrimRight(raw, "\n")
+
+		if inHunk {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				file.LinesAdded++
+			case strings.HasPrefix(line, "-"):
+				file.LinesRemoved++
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case strings.HasPrefix(line, "new file mode "):
+			file.IsNew = true
+			file.Status = StatusAdded
+			file.NewMode = strings.TrimPrefix(line, "new file mode ")
+		case strings.HasPrefix(line, "deleted file mode "):
+			file.IsDeleted = true
+			file.Status = StatusDeleted
+			file.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+		case strings.HasPrefix(line, "old mode "):
+			file.OldMode = strings.TrimPrefix(line, "old mode ")
+		case strings.HasPrefix(line, "new mode "):
+			file.NewMode = strings.TrimPrefix(line, "new mode ")
+		case strings.HasPrefix(line, "rename from "):
+			file.Status = StatusRenamed
+			oldPath = unquoteGitPath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			newPath = unquoteGitPath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "copy from "):
+			file.Status = StatusCopied
+			oldPath = unquoteGitPath(strings.TrimPrefix(line, "copy from "))
+		case strings.HasPrefix(line, "copy to "):
+			newPath = unquoteGitPath(strings.TrimPrefix(line, "copy to "))
+		case strings.HasPrefix(line, "similarity index "):
+			file.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+		case strings.HasPrefix(line, "index "):
+			// e.g. "index 3f2a1b0..9c4d5e6 100644"; the mode is only present when it did not change
+			fields := strings.Fields(strings.TrimPrefix(line, "index "))
+			if len(fields) > 0 {
+				file.OldBlob, file.NewBlob, _ = strings.Cut(fields[0], "..")
+			}
+			if len(fields) > 1 {
+				if file.OldMode == "" && !file.IsNew {
+					file.OldMode = fields[1]
+				}
+				if file.NewMode == "" && !file.IsDeleted {
+					file.NewMode = fields[1]
+				}
+			}
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			file.IsBinary = true
+		case strings.HasPrefix(line, "--- "):
+			if path := stripDiffPrefix(unquoteGitPath(strings.TrimPrefix(line, "--- "))); path != "" {
+				oldPath = path
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if path := stripDiffPrefix(unquoteGitPath(strings.TrimPrefix(line, "+++ "))); path != "" {
+				newPath = path
+			}
+		}
+	}
+
+	if err != nil {
+		// Without the "diff --git" paths, an added or deleted file only names one side
+		if oldPath == "" {
+			oldPath = newPath
+		}
+		if newPath == "" {
+			newPath = oldPath
+		}
+		if newPath == "" {
+			oldPath, newPath = unknownDiffPath, unknownDiffPath
+		}
+	}
+
+	file.OldPath, file.Path = oldPath, newPath
+	if file.IsDeleted {
+		// Deleted files are reported under the path they had
+		file.Path = oldPath
+	}
+	return file, err
+}
+
+// parseDiffGitLine extracts the old and new paths from a "diff --git a/X b/Y" line.
+// Paths with spaces are ambiguous here; the extended headers override them when present.
+func parseDiffGitLine(line string) (oldPath, newPath string, err error) {
+	rest := strings.TrimPrefix(line, "diff --git ")
+
+	// Quoted paths, e.g. diff --git "a/caf\303\251.go" "b/caf\303\251.go"
+	if strings.HasPrefix(rest, `"`) || strings.HasSuffix(rest, `"`) {
+		if first, second, ok := splitQuotedPaths(rest); ok {
+			return stripDiffPrefix(first), stripDiffPrefix(second), nil
+		}
+	}
+
+	// Unchanged path: "a/X b/X" has the same X on both sides
+	if len(rest) > 5 && (len(rest)-5)%2 == 0 {
+		half := (len(rest) - 5) / 2
+		if rest[:2] == "a/" && rest[2+half:2+half+3] == " b/" && rest[2:2+half] == rest[2+half+3:] {
+			path := rest[2 : 2+half]
+			return path, path, nil
+		}
+	}
+
+	// Renamed path without spaces
+	if idx := strings.Index(rest, " b/"); strings.HasPrefix(rest, "a/") && idx != -1 {
+		return rest[2:idx], rest[idx+3:], nil
+	}
+
+	return "", "", fmt.Errorf("unrecognized diff header %q", line)
+}
+
+// splitQuotedPaths splits the two paths of a diff header where either may be quoted
+func splitQuotedPaths(rest string) (string, string, bool) {
+	var paths []string
+	for len(rest) > 0 && len(paths) < 2 {
+		rest = strings.TrimLeft(rest, " ")
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return "", "", false
+			}
+			paths = append(paths, unquoteGitPath(quoted))
+			rest = rest[len(quoted):]
+			continue
+		}
+		end := strings.Index(rest, ` "`)
+		if end == -1 {
+			end = len(rest)
+		}
+		paths = append(paths, rest[:end])
+		rest = rest[end:]
+	}
+	if len(paths) != 2 {
+		return "", "", false
+	}
+	return paths[0], paths[1], true
+}
+
+// unquoteGitPath decodes a path git quoted because of special characters, e.g. "caf\303\251.go"
+func unquoteGitPath(path string) string {
+	// "---"/"+++" paths are followed by a tab when they contain spaces
+	path = strings.TrimSuffix(path, "\t")
+	if !strings.HasPrefix(path, `"`) {
+		return path
+	}
+	unquoted, err := strconv.Unquote(path)
+	if err != nil {
+		return path
+	}
+	return unquoted
+}
+
+// stripDiffPrefix removes the a/ or b/ prefix of a diff path, returning "" for /dev/null
+func stripDiffPrefix(path string) string {
+	if path == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		return path[2:]
+	}
+	return path
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GetEditor returns the user's preferred editor from the environment
+func GetEditor() string {
+	// Try to get the default editor from environment variables
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = os.Getenv("VISUAL")
+	}
+	if editor == "" {
+		// Default to common editors based on OS
+		editor = "vi" // Unix default
+	}
+	return editor
+}
+
+// OpenFileInEditor opens path in the user's editor and waits for it to close
+func OpenFileInEditor(path string) error {
+	editor := GetEditor()
+
+	cmd := exec.Command(editor, path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to open %s with %s: %w", path, editor, err)
+	}
+	return nil
+}
+
+// EditText lets the user edit text in their editor and returns the result
+func EditText(text string) (string, error) {
+	file, err := os.CreateTemp("", "prgen-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	file.Close()
+
+	if err := OpenFileInEditor(file.Name()); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
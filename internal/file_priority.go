@@ -0,0 +1,253 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FileClass is the broad role of a file, used as the base of its relevance score
+type FileClass string
+
+const (
+	ClassSource    FileClass = "source"
+	ClassTest      FileClass = "test"
+	ClassConfig    FileClass = "config"
+	ClassDocs      FileClass = "docs"
+	ClassGenerated FileClass = "generated"
+	ClassOther     FileClass = "other"
+)
+
+// classWeights is the base score of each file class
+var classWeights = map[FileClass]float64{
+	ClassSource:    40,
+	ClassTest:      20,
+	ClassConfig:    15,
+	ClassOther:     15,
+	ClassDocs:      10,
+	ClassGenerated: -50,
+}
+
+const (
+	// churnWeight scales the log of the number of changed lines
+	churnWeight = 4
+	// maxChurnPoints caps the churn bonus so large changes do not win on size alone
+	maxChurnPoints = 24
+	// referencePoints is added for each other changed file that uses one of the file's symbols
+	referencePoints = 8
+	// maxReferencePoints caps the reference bonus
+	maxReferencePoints = 24
+	// maxReferenceFiles skips the reference analysis for very large diffs
+	maxReferenceFiles = 300
+)
+
+// ScoreReason is one contribution to a file's relevance score
+type ScoreReason struct {
+	Label  string
+	Points float64
+}
+
+// FileScore is a file's relevance score with the reasons that make it up
+type FileScore struct {
+	File    FileChange
+	Class   FileClass
+	Score   float64
+	Reasons []ScoreReason
+}
+
+// Explain returns the score breakdown, e.g. "source +40, churn +12, referenced by 2 files +16"
+func (s FileScore) Explain() string {
+	var parts []string
+	for _, reason := range s.Reasons {
+		parts = append(parts, fmt.Sprintf("%s %+g", reason.Label, math.Round(reason.Points*10)/10))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// add records a contribution to the score
+func (s *FileScore) add(label string, points float64) {
+	if points == 0 {
+		return
+	}
+	s.Score += points
+	s.Reasons = append(s.Reasons, ScoreReason{Label: label, Points: points})
+}
+
+// ScoreFiles ranks the files by relevance to the PR, most relevant first.
+// Path weights come from the file_priority_weights config map of glob patterns to points.
+func ScoreFiles(config *Config, files []FileChange) []FileScore {
+	var pathWeights map[string]float64
+	if config != nil {
+		// An invalid map only loses the custom weights
+		_, _ = config.Decode("file_priority_weights", &pathWeights)
+	}
+	patterns := make([]string, 0, len(pathWeights))
+	for pattern := range pathWeights {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	references := findSymbolUsers(files)
+
+	scores := make([]FileScore, len(files))
+	for i, file := range files {
+		score := FileScore{File: file, Class: classifyFile(file)}
+		score.add(string(score.Class), classWeights[score.Class])
+
+		if lines := file.LinesAdded + file.LinesRemoved; lines > 0 {
+			score.add("churn", math.Min(maxChurnPoints, churnWeight*math.Log2(1+float64(lines))))
+		}
+
+		for _, pattern := range patterns {
+			if matchGlob(pattern, file.Path) {
+				score.add("path "+pattern, pathWeights[pattern])
+			}
+		}
+
+		if count := len(references[i]); count > 0 {
+			score.add(fmt.Sprintf("referenced by %d file(s)", count), math.Min(maxReferencePoints, float64(count*referencePoints)))
+		}
+
+		scores[i] = score
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].File.Path < scores[j].File.Path
+	})
+	return scores
+}
+
+// ExplainFilePriorities scores the files changed on the current branch, most relevant first
+func ExplainFilePriorities(config *Config) ([]FileScore, error) {
+	diff, err := GetDiff(ResolveCurrentBase(config).Branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff: %w", err)
+	}
+
+	files, err := parseChangedFiles(diff)
+	if err != nil {
+		return nil, err
+	}
+	return ScoreFiles(config, files), nil
+}
+
+// classifyFile decides whether a file is source, test, docs, config or generated from its path
+func classifyFile(file FileChange) FileClass {
+	if file.IsGenerated || isLockfile(file.Path) {
+		return ClassGenerated
+	}
+
+	lower := strings.ToLower(file.Path)
+	base := path.Base(lower)
+	ext := path.Ext(base)
+
+	switch {
+	case strings.HasSuffix(base, "_test.go"), strings.Contains(base, ".test."), strings.Contains(base, ".spec."),
+		strings.HasPrefix(base, "test_") && ext == ".py", strings.HasSuffix(base, "_test.py"),
+		hasPathSegment(lower, "test", "tests", "__tests__", "testdata", "spec"):
+		return ClassTest
+	case ext == ".md" || ext == ".rst" || ext == ".adoc" || ext == ".txt",
+		hasPathSegment(lower, "docs", "doc"):
+		return ClassDocs
+	case ext == ".json" || ext == ".yaml" || ext == ".yml" || ext == ".toml" || ext == ".ini" || ext == ".cfg" || ext == ".env",
+		base == "dockerfile" || base == "makefile" || base == "go.mod",
+		strings.HasPrefix(lower, ".github/") || strings.HasPrefix(base, "."):
+		return ClassConfig
+	case sourceExtensions[ext]:
+		return ClassSource
+	default:
+		return ClassOther
+	}
+}
+
+// sourceExtensions lists the extensions of program source files
+var sourceExtensions = map[string]bool{
+	".go": true, ".py": true, ".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".java": true,
+	".kt": true, ".rs": true, ".rb": true, ".php": true, ".c": true, ".h": true, ".cc": true, ".cpp": true,
+	".hpp": true, ".cs": true, ".swift": true, ".scala": true, ".sh": true, ".sql": true, ".vue": true,
+	".svelte": true, ".dart": true, ".ex": true, ".exs": true, ".lua": true,
+}
+
+// hasPathSegment reports whether any directory of the path has one of the given names
+func hasPathSegment(filePath string, names ...string) bool {
+	segments := strings.Split(filePath, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		for _, name := range names {
+			if segment == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// symbolDefinitionPattern finds names defined on changed lines in common languages
+var symbolDefinitionPattern = regexp.MustCompile(`(?m)^[+-]\s*(?:export\s+)?(?:default\s+)?(?:pub\s+)?(?:async\s+)?` +
+	`(?:func\s+(?:\([^)]*\)\s*)?|type\s+|def\s+|class\s+|function\s+|fn\s+|struct\s+|interface\s+|enum\s+|const\s+|let\s+|var\s+)` +
+	`([A-Za-z_][A-Za-z0-9_]*)`)
+
+// minSymbolLength skips short names that would match unrelated words
+const minSymbolLength = 4
+
+// findSymbolUsers returns, for each file, the indexes of the other changed files that mention a symbol it defines or changes
+func findSymbolUsers(files []FileChange) [][]int {
+	users := make([][]int, len(files))
+	if len(files) > maxReferenceFiles {
+		return users
+	}
+
+	changedLines := make([]string, len(files))
+	for i, file := range files {
+		if !file.IsGenerated && !file.IsBinary {
+			changedLines[i] = extractChangedLines(file.Content)
+		}
+	}
+
+	for i, file := range files {
+		if file.IsGenerated || changedLines[i] == "" {
+			continue
+		}
+
+		var symbols []string
+		seen := make(map[string]bool)
+		for _, match := range symbolDefinitionPattern.FindAllStringSubmatch(changedLines[i], -1) {
+			if name := match[1]; len(name) >= minSymbolLength && !seen[name] {
+				seen[name] = true
+				symbols = append(symbols, regexp.QuoteMeta(name))
+			}
+		}
+		if len(symbols) == 0 {
+			continue
+		}
+
+		usage := regexp.MustCompile(`\b(?:` + strings.Join(symbols, "|") + `)\b`)
+		for j := range files {
+			if j != i && changedLines[j] != "" && usage.MatchString(changedLines[j]) {
+				users[i] = append(users[i], j)
+			}
+		}
+	}
+	return users
+}
+
+// extractChangedLines returns the added and removed lines of a file's diff, with their +/- markers
+func extractChangedLines(content string) string {
+	var result strings.Builder
+	inHunk := false
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+			continue
+		}
+		if inHunk && (strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")) {
+			result.WriteString(line + "\n")
+		}
+	}
+	return result.String()
+}
@@ -2,16 +2,127 @@ package internal
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
-// GetDiff executes 'git diff' between the current branch and main branch.
+// DefaultBaseBranch is the trunk PRs are opened against when base_branch is not configured
+const DefaultBaseBranch = "main"
+
+// diffArgs starts every diff prgen parses. The prefixes, external diff drivers and colors
+// are fixed so that user settings such as diff.noprefix or diff.mnemonicPrefix do not change the format.
+var diffArgs = []string{"diff", "--no-ext-diff", "--no-color", "--src-prefix=a/", "--dst-prefix=b/"}
+
+// GetDiff executes 'git diff' between the current branch and its base branch.
 // It returns the diff output showing changes that would be included in a PR.
 // Returns an empty string and nil error if there are no changes,
 // or returns an error if the git command fails.
-func GetDiff() (string, error) {
-	cmd := exec.Command("git", "diff", "main...HEAD")
+func GetDiff(base string) (string, error) {
+	cmd := exec.Command("git", append(diffArgs, "--find-renames", "--find-copies", base+"...HEAD")...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetRemoteURL gets the URL of the named remote
+func GetRemoteURL(remote string) (string, error) {
+	cmd := exec.Command("git", "remote", "get-url", remote)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ListRemotes returns the names of the configured git remotes
+func ListRemotes() ([]string, error) {
+	cmd := exec.Command("git", "remote")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// Remotes holds the git remotes used for pushing the branch and opening the PR
+type Remotes struct {
+	Push string // Remote the branch is pushed to, e.g. a fork
+	Base string // Remote of the repository the PR is opened against
+}
+
+// IsFork reports whether the branch is pushed to a different remote than the PR target
+func (r Remotes) IsFork() bool {
+	return r.Push != r.Base
+}
+
+// ResolveRemotes determines the push and base remotes from the config.
+// When neither is configured, the common fork layout (origin = fork, upstream = base) is auto-detected.
+func ResolveRemotes(config *Config) (Remotes, error) {
+	remotes, err := ListRemotes()
+	if err != nil {
+		return Remotes{}, fmt.Errorf("failed to list git remotes: %w", err)
+	}
+	if len(remotes) == 0 {
+		return Remotes{}, fmt.Errorf("no git remotes configured")
+	}
+
+	exists := make(map[string]bool, len(remotes))
+	for _, remote := range remotes {
+		exists[remote] = true
+	}
+
+	push := config.GetString("push_remote", "")
+	base := config.GetString("base_remote", "")
+
+	if push == "" {
+		switch {
+		case exists["origin"]:
+			push = "origin"
+		case base != "":
+			push = base
+		default:
+			push = remotes[0]
+		}
+	}
+	if base == "" {
+		if exists["upstream"] && push != "upstream" {
+			base = "upstream"
+		} else {
+			base = push
+		}
+	}
+
+	for _, remote := range []string{push, base} {
+		if !exists[remote] {
+			return Remotes{}, fmt.Errorf("git remote %q does not exist (available: %s)", remote, strings.Join(remotes, ", "))
+		}
+	}
+
+	return Remotes{Push: push, Base: base}, nil
+}
+
+// GetDiffIncludingStaged is like GetDiff but also includes changes staged in the index
+func GetDiffIncludingStaged(base string) (string, error) {
+	mergeBase, err := getMergeBase(base, "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("git", append(diffArgs, "--cached", "--find-renames", "--find-copies", mergeBase)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetStagedDiff returns the changes staged in the index
+func GetStagedDiff() (string, error) {
+	cmd := exec.Command("git", append(diffArgs, "--cached", "--find-renames", "--find-copies")...)
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -19,9 +130,110 @@ func GetDiff() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// GetRemoteOrigin gets the origin remote URL
-func GetRemoteOrigin() (string, error) {
-	cmd := exec.Command("git", "remote", "get-url", "origin")
+// getMergeBase returns the best common ancestor of two revisions
+func getMergeBase(a, b string) (string, error) {
+	cmd := exec.Command("git", "merge-base", a, b)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find merge base of %s and %s: %w", a, b, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// WorkingTreeStatus lists the uncommitted changes in the repository
+type WorkingTreeStatus struct {
+	Staged    []string // Changes in the index
+	Unstaged  []string // Modified tracked files not yet staged
+	Untracked []string // Files git does not track
+}
+
+// IsDirty reports whether there are any uncommitted changes
+func (s *WorkingTreeStatus) IsDirty() bool {
+	return len(s.Staged) > 0 || len(s.Unstaged) > 0 || len(s.Untracked) > 0
+}
+
+// GetWorkingTreeStatus reads the uncommitted changes from git status
+func GetWorkingTreeStatus() (*WorkingTreeStatus, error) {
+	// -z keeps paths unquoted and separates them with NUL
+	cmd := exec.Command("git", "status", "--porcelain=v1", "-z")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git status: %w", err)
+	}
+
+	status := &WorkingTreeStatus{}
+	entries := strings.Split(string(output), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+
+		// Format is "XY path", X = index status, Y = worktree status
+		index, worktree, path := entry[0], entry[1], entry[3:]
+		// Renames and copies are followed by their source path, which is not a change of its own
+		if index == 'R' || index == 'C' || worktree == 'R' || worktree == 'C' {
+			i++
+		}
+		if index == '?' && worktree == '?' {
+			status.Untracked = append(status.Untracked, path)
+			continue
+		}
+		if index != ' ' {
+			status.Staged = append(status.Staged, path)
+		}
+		if worktree != ' ' {
+			status.Unstaged = append(status.Unstaged, path)
+		}
+	}
+
+	return status, nil
+}
+
+// GetTrackedChangesDiff returns the uncommitted changes of tracked files, staged or not,
+// i.e. what StageTrackedChanges followed by CommitStaged would commit
+func GetTrackedChangesDiff() (string, error) {
+	cmd := exec.Command("git", append(diffArgs, "--find-renames", "--find-copies", "HEAD")...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ResetIndex unstages everything, restoring the index to HEAD without touching the working tree
+func ResetIndex() error {
+	output, err := exec.Command("git", "reset", "-q").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to reset the index: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// StageTrackedChanges stages all modifications and deletions of tracked files
+func StageTrackedChanges() error {
+	cmd := exec.Command("git", "add", "--update")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to stage changes: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// CommitStaged commits the staged changes with the given message
+func CommitStaged(message string) error {
+	cmd := exec.Command("git", "commit", "--file", "-")
+	cmd.Stdin = strings.NewReader(message)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git commit failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// GetRepoRoot gets the top-level directory of the current git repository
+func GetRepoRoot() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -39,19 +251,307 @@ func GetCurrentBranch() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// PushCurrentBranch pushes the current branch to origin
-func PushCurrentBranch() error {
-	// Get current branch name
+// issueTrailerKeys are the commit trailers that reference issues
+var issueTrailerKeys = []string{"Fixes", "Closes", "Resolves", "Refs", "Issue"}
+
+// GetCommitTrailers returns the values of the issue trailers (e.g. "Refs: PROJ-123") of the commits on the current branch since base
+func GetCommitTrailers(base string) ([]string, error) {
+	cmd := exec.Command("git", "log", base+"..HEAD", "--format=%(trailers:only,unfold)")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found || !isIssueTrailerKey(strings.TrimSpace(key)) {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// isIssueTrailerKey reports whether a trailer key references issues, ignoring case
+func isIssueTrailerKey(key string) bool {
+	for _, issueKey := range issueTrailerKeys {
+		if strings.EqualFold(key, issueKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePushBranch returns the current branch, refusing a detached HEAD or the trunk itself
+func ValidatePushBranch(trunk string) (string, error) {
 	branch, err := GetCurrentBranch()
 	if err != nil {
-		return err
+		return "", err
+	}
+	if branch == "" {
+		return "", fmt.Errorf("HEAD is detached; check out a branch before creating a PR")
+	}
+	if branch == trunk {
+		return "", fmt.Errorf("you are on the base branch %s; create a feature branch before creating a PR", trunk)
+	}
+	return branch, nil
+}
+
+// PushState describes how the local branch relates to its remote counterpart
+type PushState int
+
+const (
+	PushNew         PushState = iota // Branch does not exist on the remote yet
+	PushUpToDate                     // Remote already points at the local commit
+	PushFastForward                  // Local branch is ahead of the remote
+	PushBehind                       // Remote has commits the local branch lacks
+	PushDiverged                     // Both sides have commits the other lacks (e.g. after a rebase)
+)
+
+// PushStatus holds the result of comparing the local and remote branch
+type PushStatus struct {
+	Remote    string
+	Branch    string
+	LocalSHA  string
+	RemoteSHA string
+	State     PushState
+	Ahead     int // Commits on the local branch missing from the remote
+	Behind    int // Commits on the remote branch missing locally
+}
+
+// CheckPushStatus compares the current branch with the same branch on the remote
+func CheckPushStatus(remote, branch string) (*PushStatus, error) {
+	localSHA, err := revParse("HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	remoteSHA, err := getRemoteBranchSHA(remote, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &PushStatus{
+		Remote:    remote,
+		Branch:    branch,
+		LocalSHA:  localSHA,
+		RemoteSHA: remoteSHA,
+	}
+
+	switch {
+	case remoteSHA == "":
+		status.State = PushNew
+		return status, nil
+	case remoteSHA == localSHA:
+		status.State = PushUpToDate
+		return status, nil
+	}
+
+	// Make sure the remote commit is available locally before comparing histories
+	if _, err := revParse(remoteSHA + "^{commit}"); err != nil {
+		cmd := exec.Command("git", "fetch", remote, branch)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s/%s: %s", remote, branch, strings.TrimSpace(string(output)))
+		}
+	}
+
+	status.Behind, status.Ahead, err = countDivergence(remoteSHA, localSHA)
+	if err != nil {
+		return nil, err
 	}
 
-	// Push current branch to origin with upstream tracking
-	cmd := exec.Command("git", "push", "-u", "origin", branch)
+	switch {
+	case status.Behind == 0:
+		status.State = PushFastForward
+	case status.Ahead == 0:
+		status.State = PushBehind
+	default:
+		status.State = PushDiverged
+	}
+
+	return status, nil
+}
+
+// Explain returns a human readable description of a push status that needs attention
+func (s *PushStatus) Explain() string {
+	switch s.State {
+	case PushBehind:
+		return fmt.Sprintf("%s/%s has %d commit(s) that your local branch does not have. Pull them first or force push to discard them.",
+			s.Remote, s.Branch, s.Behind)
+	case PushDiverged:
+		return fmt.Sprintf("Your local branch and %s/%s have diverged (%d local and %d remote commit(s) differ), most likely because of a rebase or amend. A normal push would be rejected.",
+			s.Remote, s.Branch, s.Ahead, s.Behind)
+	default:
+		return ""
+	}
+}
+
+// revParse resolves a revision to a commit SHA
+func revParse(rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", rev)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// getRemoteBranchSHA returns the SHA the branch points at on the remote, or "" if it does not exist there
+func getRemoteBranchSHA(remote, branch string) (string, error) {
+	cmd := exec.Command("git", "ls-remote", "--heads", remote, "refs/heads/"+branch)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("failed to query remote %s: %s", remote, strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return "", fmt.Errorf("failed to query remote %s: %w", remote, err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], nil
+}
+
+// AddTemporaryWorktree checks out rev in a new worktree in a temporary directory.
+// The returned function removes the worktree again.
+func AddTemporaryWorktree(rev string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "prgen-worktree-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+
+	output, err := exec.Command("git", "worktree", "add", "--detach", "--quiet", dir, rev).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("failed to check out %s in a worktree: %s", rev, strings.TrimSpace(string(output)))
+	}
+
+	cleanup := func() {
+		exec.Command("git", "worktree", "remove", "--force", dir).Run()
+		os.RemoveAll(dir)
+	}
+	return dir, cleanup, nil
+}
+
+// isAncestor reports whether commit a is reachable from b
+func isAncestor(a, b string) bool {
+	return exec.Command("git", "merge-base", "--is-ancestor", a, b).Run() == nil
+}
+
+// countCommits counts the commits reachable from to but not from
+func countCommits(from, to string) (int, error) {
+	output, err := exec.Command("git", "rev-list", "--count", from+".."+to).Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits in %s..%s: %w", from, to, err)
+	}
+	return strconv.Atoi(strings.TrimSpace(string(output)))
+}
+
+// countDivergence counts the commits only reachable from left and only reachable from right
+func countDivergence(left, right string) (leftOnly, rightOnly int, err error) {
+	cmd := exec.Command("git", "rev-list", "--left-right", "--count", left+"..."+right)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compare %s and %s: %w", left, right, err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rev-list output: %s", string(output))
+	}
+	leftOnly, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	rightOnly, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return leftOnly, rightOnly, nil
+}
+
+// Commit holds the SHA and message of a commit
+type Commit struct {
+	SHA     string
+	Subject string
+	Body    string
+}
+
+// GetFirstParentCommits returns the commits reachable from to but not from, following only
+// first parents so that each merged PR appears once as its merge or squash commit
+func GetFirstParentCommits(from, to string) ([]Commit, error) {
+	// Fields are separated by \x1f and commits by \x1e to survive multi-line bodies
+	cmd := exec.Command("git", "log", "--first-parent", "--format=%H%x1f%s%x1f%b%x1e", from+".."+to)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git log %s..%s failed: %s", from, to, strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(string(output), "\x1e") {
+		fields := strings.Split(strings.TrimLeft(record, "\n"), "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+		commits = append(commits, Commit{
+			SHA:     fields[0],
+			Subject: fields[1],
+			Body:    strings.TrimSpace(fields[2]),
+		})
+	}
+	return commits, nil
+}
+
+// PushBranch pushes a local branch to the given remote with upstream tracking
+func PushBranch(remote, branch string) error {
+	cmd := exec.Command("git", "push", "-u", remote, branch)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to push branch %s to %s: %s", branch, remote, string(output))
+	}
+	return nil
+}
+
+// SetUpstream makes remote/branch the upstream of branch unless it already is, e.g. when the
+// branch was pushed without -u and the push is skipped because the remote is up to date
+func SetUpstream(remote, branch string) error {
+	upstream := remote + "/" + branch
+	current, err := exec.Command("git", "rev-parse", "--abbrev-ref", branch+"@{upstream}").Output()
+	if err == nil && strings.TrimSpace(string(current)) == upstream {
+		return nil
+	}
+
+	// --set-upstream-to needs the remote-tracking branch, which a push from elsewhere does not create
+	if _, err := revParse("refs/remotes/" + upstream); err != nil {
+		output, err := exec.Command("git", "fetch", remote, branch).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %s", upstream, strings.TrimSpace(string(output)))
+		}
+	}
+
+	output, err := exec.Command("git", "branch", "--set-upstream-to="+upstream, branch).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to set the upstream of %s to %s: %s", branch, upstream, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ForcePushBranch force pushes a local branch, but only if the remote
+// still points at expectedSHA so that nobody else's commits are overwritten
+func ForcePushBranch(remote, branch, expectedSHA string) error {
+	lease := fmt.Sprintf("--force-with-lease=refs/heads/%s:%s", branch, expectedSHA)
+	cmd := exec.Command("git", "push", "-u", lease, remote, branch)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("failed to push branch %s: %s", branch, string(output))
+		return fmt.Errorf("failed to force push branch %s to %s: %s", branch, remote, string(output))
 	}
 
 	return nil
@@ -1,21 +1,152 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
 	"os/exec"
 	"strings"
 )
 
+// PRState represents the state a pull request is created in
+type PRState string
+
+const (
+	PRStateDraft     PRState = "draft"
+	PRStateReady     PRState = "ready"
+	PRStateAutoMerge PRState = "auto-merge"
+)
+
+// DefaultMergeMethod is the merge method used for auto-merge when none is configured
+const DefaultMergeMethod = "squash"
+
+// ParsePRState converts a config or flag value into a PRState
+func ParsePRState(value string) (PRState, error) {
+	switch PRState(strings.ToLower(strings.TrimSpace(value))) {
+	case PRStateDraft:
+		return PRStateDraft, nil
+	case PRStateReady:
+		return PRStateReady, nil
+	case PRStateAutoMerge:
+		return PRStateAutoMerge, nil
+	default:
+		return "", fmt.Errorf("invalid PR state %q (expected draft, ready or auto-merge)", value)
+	}
+}
+
+// Next returns the state that follows s when toggling in the review screen
+func (s PRState) Next() PRState {
+	switch s {
+	case PRStateDraft:
+		return PRStateReady
+	case PRStateReady:
+		return PRStateAutoMerge
+	default:
+		return PRStateDraft
+	}
+}
+
+// ValidateMergeMethod checks that method is a merge method supported by gh
+func ValidateMergeMethod(method string) error {
+	switch method {
+	case "merge", "squash", "rebase":
+		return nil
+	default:
+		return fmt.Errorf("invalid merge method %q (expected merge, squash or rebase)", method)
+	}
+}
+
+// PROptions controls how the pull request is created
+type PROptions struct {
+	State       PRState // Draft, ready for review, or ready with auto-merge
+	MergeMethod string  // Merge method used when State is PRStateAutoMerge
+}
+
+// ResolvePROptions builds the PR options from the config, overridden by non-empty run flags
+func ResolvePROptions(config *Config, stateFlag, mergeMethodFlag string) (PROptions, error) {
+	stateValue := config.GetString("pr_state", string(PRStateDraft))
+	if stateFlag != "" {
+		stateValue = stateFlag
+	}
+	state, err := ParsePRState(stateValue)
+	if err != nil {
+		return PROptions{}, err
+	}
+
+	mergeMethod := config.GetString("merge_method", DefaultMergeMethod)
+	if mergeMethodFlag != "" {
+		mergeMethod = mergeMethodFlag
+	}
+	if err := ValidateMergeMethod(mergeMethod); err != nil {
+		return PROptions{}, err
+	}
+
+	return PROptions{State: state, MergeMethod: mergeMethod}, nil
+}
+
+// PRTarget identifies the repository the PR is opened against and the branch it comes from
+type PRTarget struct {
+	Repo string // owner/repo of the base repository
+	Head string // Head branch, prefixed with "owner:" for cross-repository PRs
+	Base string // Branch the PR is opened against
+}
+
+// ResolvePRTarget builds the PR target for branch from the push and base remotes
+func ResolvePRTarget(remotes Remotes, branch string) (PRTarget, error) {
+	baseURL, err := GetRemoteURL(remotes.Base)
+	if err != nil {
+		return PRTarget{}, fmt.Errorf("failed to get URL of remote %s: %w", remotes.Base, err)
+	}
+	baseOwner, baseRepo, err := ParseGitHubRepo(baseURL)
+	if err != nil {
+		return PRTarget{}, err
+	}
+
+	target := PRTarget{
+		Repo: baseOwner + "/" + baseRepo,
+		Head: branch,
+	}
+	if !remotes.IsFork() {
+		return target, nil
+	}
+
+	pushURL, err := GetRemoteURL(remotes.Push)
+	if err != nil {
+		return PRTarget{}, fmt.Errorf("failed to get URL of remote %s: %w", remotes.Push, err)
+	}
+	pushOwner, _, err := ParseGitHubRepo(pushURL)
+	if err != nil {
+		return PRTarget{}, err
+	}
+	if pushOwner != baseOwner {
+		target.Head = pushOwner + ":" + branch
+	}
+
+	return target, nil
+}
+
 // CreateGitHubPR creates a pull request using gh CLI
-func CreateGitHubPR(title, body string) (string, error) {
+func CreateGitHubPR(title, body string, target PRTarget, opts PROptions) (string, error) {
 	// Check if gh CLI is available
 	if err := checkGHCLI(); err != nil {
 		return "", err
 	}
 
-	// Use gh CLI to create PR in draft state
-	// gh pr create --title "title" --body "body" --base main --draft
-	cmd := exec.Command("gh", "pr", "create", "--title", title, "--body", body, "--base", "main", "--draft")
+	if target.Base == "" {
+		return "", fmt.Errorf("no base branch to open the PR against")
+	}
+
+	// gh pr create --title "title" --body "body" --base main [--repo owner/repo --head owner:branch] [--draft]
+	args := []string{"pr", "create", "--title", title, "--body", body, "--base", target.Base}
+	if target.Repo != "" {
+		args = append(args, "--repo", target.Repo)
+	}
+	if target.Head != "" {
+		args = append(args, "--head", target.Head)
+	}
+	if opts.State == PRStateDraft {
+		args = append(args, "--draft")
+	}
+	cmd := exec.Command("gh", args...)
 
 	output, err := cmd.Output()
 	if err != nil {
@@ -27,9 +158,135 @@ func CreateGitHubPR(title, body string) (string, error) {
 
 	// Return the PR URL from gh output
 	prURL := strings.TrimSpace(string(output))
+
+	if opts.State == PRStateAutoMerge {
+		if err := EnableAutoMerge(prURL, opts.MergeMethod); err != nil {
+			return prURL, err
+		}
+	}
+
 	return prURL, nil
 }
 
+// GetPRBody returns the current body of the PR
+func GetPRBody(prURL string) (string, error) {
+	cmd := exec.Command("gh", "pr", "view", prURL, "--json", "body", "--jq", ".body")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("gh pr view failed: %s", strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return "", fmt.Errorf("failed to execute gh pr view: %w", err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// UpdatePRBody replaces the body of the PR
+func UpdatePRBody(prURL, body string) error {
+	cmd := exec.Command("gh", "pr", "edit", prURL, "--body-file", "-")
+	cmd.Stdin = strings.NewReader(body)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gh pr edit failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// EnableAutoMerge turns on auto-merge for the given PR with the chosen merge method
+func EnableAutoMerge(prURL, mergeMethod string) error {
+	// gh pr merge <url> --auto --squash
+	cmd := exec.Command("gh", "pr", "merge", prURL, "--auto", "--"+mergeMethod)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("PR created but enabling auto-merge failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// FindOpenPR returns the URL of the open PR for the target's head branch, or "" if there is none
+func FindOpenPR(target PRTarget) (string, error) {
+	if err := checkGHCLI(); err != nil {
+		return "", err
+	}
+
+	// gh pr list filters by branch name only, without the "owner:" prefix, so PRs
+	// from same-named branches of other forks are filtered out by their owner below
+	owner, branch := "", target.Head
+	if idx := strings.Index(branch, ":"); idx != -1 {
+		owner, branch = branch[:idx], branch[idx+1:]
+	}
+
+	args := []string{"pr", "list", "--head", branch, "--state", "open", "--json", "url,headRefName,headRepositoryOwner,isCrossRepository"}
+	if target.Repo != "" {
+		args = append(args, "--repo", target.Repo)
+	}
+	cmd := exec.Command("gh", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("gh pr list failed: %s", string(exitError.Stderr))
+		}
+		return "", fmt.Errorf("failed to execute gh pr list: %w", err)
+	}
+
+	var prs []struct {
+		URL                 string `json:"url"`
+		HeadRefName         string `json:"headRefName"`
+		IsCrossRepository   bool   `json:"isCrossRepository"`
+		HeadRepositoryOwner struct {
+			Login string `json:"login"`
+		} `json:"headRepositoryOwner"`
+	}
+	if err := json.Unmarshal(output, &prs); err != nil {
+		return "", fmt.Errorf("failed to parse gh pr list output: %w", err)
+	}
+	for _, pr := range prs {
+		if pr.HeadRefName != branch {
+			continue
+		}
+		// Without an owner the head branch is in the base repository itself
+		if (owner == "" && !pr.IsCrossRepository) || (owner != "" && strings.EqualFold(pr.HeadRepositoryOwner.Login, owner)) {
+			return pr.URL, nil
+		}
+	}
+	return "", nil
+}
+
+// ParseGitHubRepo extracts the owner and repository name from a GitHub remote URL.
+// Both SSH (git@github.com:owner/repo.git) and HTTPS URLs are supported.
+func ParseGitHubRepo(remoteURL string) (owner, repo string, err error) {
+	path := strings.TrimSuffix(strings.TrimSpace(remoteURL), ".git")
+	if idx := strings.Index(path, "://"); idx != -1 {
+		// https://github.com/owner/repo or ssh://git@github.com/owner/repo
+		path = path[idx+3:]
+		if slash := strings.Index(path, "/"); slash != -1 {
+			path = path[slash+1:]
+		}
+	} else if colon := strings.Index(path, ":"); colon != -1 {
+		// git@github.com:owner/repo
+		path = path[colon+1:]
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || parts[len(parts)-2] == "" || parts[len(parts)-1] == "" {
+		return "", "", fmt.Errorf("could not determine owner and repo from remote URL %s", remoteURL)
+	}
+	return parts[len(parts)-2], parts[len(parts)-1], nil
+}
+
+// ResolveBaseRepo returns the owner and name of the repository PRs are opened against
+func ResolveBaseRepo(config *Config) (owner, repo string, err error) {
+	remotes, err := ResolveRemotes(config)
+	if err != nil {
+		return "", "", err
+	}
+	remoteURL, err := GetRemoteURL(remotes.Base)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get URL of remote %s: %w", remotes.Base, err)
+	}
+	return ParseGitHubRepo(remoteURL)
+}
+
 // checkGHCLI verifies that gh CLI is installed and authenticated
 func checkGHCLI() error {
 	// Check if gh is installed
@@ -47,8 +304,8 @@ func checkGHCLI() error {
 	return nil
 }
 
-// OpenPRInBrowser opens the current PR in the web browser
-func OpenPRInBrowser() error {
-	cmd := exec.Command("gh", "pr", "view", "--web")
+// OpenPRInBrowser opens the given PR in the web browser
+func OpenPRInBrowser(prURL string) error {
+	cmd := exec.Command("gh", "pr", "view", prURL, "--web")
 	return cmd.Run()
 }
@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// GoSummarizer summarizes Go files by their exported API: functions, methods and types
+type GoSummarizer struct{}
+
+// goDecl is a top-level declaration with its signature and a hash of its full source
+type goDecl struct {
+	signature string
+	hash      [32]byte
+	exported  bool
+}
+
+// Name identifies the Go summarizer
+func (s *GoSummarizer) Name() string {
+	return "go"
+}
+
+// Supports reports whether path is a Go source file
+func (s *GoSummarizer) Supports(path string) bool {
+	return strings.HasSuffix(path, ".go")
+}
+
+// Summarize lists the added, removed and changed exported declarations between the two versions
+func (s *GoSummarizer) Summarize(path string, before, after []byte) (string, error) {
+	oldDecls, err := parseGoDecls(path, before)
+	if err != nil {
+		return "", err
+	}
+	newDecls, err := parseGoDecls(path, after)
+	if err != nil {
+		return "", err
+	}
+
+	var added, removed, signatureChanged, changed []string
+	unexportedChanges := 0
+
+	for key, newDecl := range newDecls {
+		oldDecl, ok := oldDecls[key]
+		switch {
+		case !ok && newDecl.exported:
+			added = append(added, newDecl.signature)
+		case !ok:
+			unexportedChanges++
+		case oldDecl.hash == newDecl.hash:
+			// Unchanged
+		case !newDecl.exported:
+			unexportedChanges++
+		case oldDecl.signature != newDecl.signature:
+			signatureChanged = append(signatureChanged, oldDecl.signature+" -> "+newDecl.signature)
+		default:
+			changed = append(changed, newDecl.signature)
+		}
+	}
+	for key, oldDecl := range oldDecls {
+		if _, ok := newDecls[key]; ok {
+			continue
+		}
+		if oldDecl.exported {
+			removed = append(removed, oldDecl.signature)
+		} else {
+			unexportedChanges++
+		}
+	}
+
+	var result strings.Builder
+	writeDeclList(&result, "added", added)
+	writeDeclList(&result, "removed", removed)
+	writeDeclList(&result, "signature changed", signatureChanged)
+	writeDeclList(&result, "changed", changed)
+	if unexportedChanges > 0 {
+		result.WriteString(fmt.Sprintf("unexported declarations added, removed or changed: %d\n", unexportedChanges))
+	}
+	if result.Len() == 0 {
+		result.WriteString("no declaration changes (imports, comments or formatting only)\n")
+	}
+	return result.String(), nil
+}
+
+// writeDeclList writes one line per declaration under a label, sorted for stable output
+func writeDeclList(result *strings.Builder, label string, decls []string) {
+	sort.Strings(decls)
+	for _, decl := range decls {
+		result.WriteString(label + ": " + decl + "\n")
+	}
+}
+
+// parseGoDecls parses a Go file and returns its top-level functions, methods and types by name.
+// A nil source (missing side of a new or deleted file) has no declarations.
+func parseGoDecls(path string, src []byte) (map[string]goDecl, error) {
+	decls := make(map[string]goDecl)
+	if src == nil {
+		return decls, nil
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			key, receiver := d.Name.Name, ""
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				receiver = receiverTypeName(d.Recv.List[0].Type)
+				key = receiver + "." + key
+			}
+			// The signature is the declaration without its body
+			signature := printGoNode(fset, &ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type})
+			decls[key] = goDecl{
+				signature: signature,
+				hash:      sha256.Sum256([]byte(printGoNode(fset, d))),
+				exported:  d.Name.IsExported() && (receiver == "" || ast.IsExported(receiver)),
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				decls["type "+typeSpec.Name.Name] = goDecl{
+					signature: "type " + typeSpec.Name.Name + " " + typeKind(fset, typeSpec.Type),
+					hash:      sha256.Sum256([]byte(printGoNode(fset, typeSpec))),
+					exported:  typeSpec.Name.IsExported(),
+				}
+			}
+		}
+	}
+	return decls, nil
+}
+
+// typeKind describes a type definition briefly: "struct" and "interface" rather than their fields
+func typeKind(fset *token.FileSet, expr ast.Expr) string {
+	switch expr.(type) {
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "interface"
+	default:
+		return printGoNode(fset, expr)
+	}
+}
+
+// receiverTypeName returns the type name of a method receiver such as *T or T[K]
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	default:
+		return ""
+	}
+}
+
+// printGoNode formats an AST node as source on a single line
+func printGoNode(fset *token.FileSet, node ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
@@ -0,0 +1,296 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultHistoryLimit is how many sessions are kept when history_limit is not configured
+const DefaultHistoryLimit = 100
+
+// SessionStatus describes how far a session got
+type SessionStatus string
+
+const (
+	SessionInProgress SessionStatus = "in-progress" // Generated but no PR yet
+	SessionCreated    SessionStatus = "created"     // PR was created
+	SessionCancelled  SessionStatus = "cancelled"   // Cancelled by the user
+)
+
+// Iteration is one generated or refined version of the PR content
+type Iteration struct {
+	Feedback  string    `json:"feedback,omitempty"` // Empty for the initial generation
+	Parent    int       `json:"parent,omitempty"`   // Version this one was refined from, 0 for the initial generation
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session is the persisted state of one PR generation run
+type Session struct {
+	ID           string             `json:"id"`
+	RepoRoot     string             `json:"repo_root"`
+	Branch       string             `json:"branch"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	DiffHash     string             `json:"diff_hash"`
+	Background   string             `json:"background,omitempty"`
+	LLMSessionID string             `json:"llm_session_id"`        // Provider session used for refinement
+	LLMVersion   int                `json:"llm_version,omitempty"` // Version of the provider's last response, 0 if it was not a PR
+	IssueRefs    []IssueRef         `json:"issue_refs,omitempty"`
+	ReviewNotes  bool               `json:"review_notes,omitempty"`
+	Findings     []ReviewFinding    `json:"findings,omitempty"`
+	Dependencies []DependencyChange `json:"dependencies,omitempty"`
+	Stack        []StackEntry       `json:"stack,omitempty"`    // Branches of the stack with their PRs, bottom first
+	Staged       bool               `json:"staged,omitempty"`   // The diff included staged changes
+	Sections     []PromptSection    `json:"sections,omitempty"` // Prompt sections reused when refining after a resume
+	Benchmark    *BenchmarkReport   `json:"benchmark,omitempty"`
+	Iterations   []Iteration        `json:"iterations"`
+	Selected     int                `json:"selected,omitempty"` // Version chosen with undo/redo, 0 for the latest
+	PRURL        string             `json:"pr_url,omitempty"`
+	Status       SessionStatus      `json:"status"`
+}
+
+// NewSession creates a session for the current repository and branch
+func NewSession(diff, background string) *Session {
+	now := time.Now()
+	repoRoot, _ := GetRepoRoot()
+	branch, _ := GetCurrentBranch()
+
+	return &Session{
+		ID:         newSessionID(now),
+		RepoRoot:   repoRoot,
+		Branch:     branch,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		DiffHash:   HashDiff(diff),
+		Background: background,
+		Status:     SessionInProgress,
+	}
+}
+
+// sessionIDPattern matches the IDs created by newSessionID, e.g. "20250101-120000-ab12"
+var sessionIDPattern = regexp.MustCompile(`^\d{8}-\d{6}-[0-9a-f]{4}$`)
+
+// newSessionID returns a sortable, unique session ID such as 20250102-150405-a1b2
+func newSessionID(now time.Time) string {
+	suffix := make([]byte, 2)
+	_, _ = rand.Read(suffix)
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// HashDiff returns a short hash identifying the diff a session was generated from
+func HashDiff(diff string) string {
+	hash := sha256.Sum256([]byte(diff))
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// AddIteration records a generated or refined version as the current candidate.
+// It is refined from the current version, which may be an earlier one after an undo.
+func (s *Session) AddIteration(feedback, title, body string) {
+	var parent int
+	if len(s.Iterations) > 0 {
+		parent = s.Version()
+	}
+
+	s.Iterations = append(s.Iterations, Iteration{
+		Feedback:  feedback,
+		Parent:    parent,
+		Title:     title,
+		Body:      body,
+		CreatedAt: time.Now(),
+	})
+	s.Selected = 0
+}
+
+// Version returns the 1-based number of the current candidate, 0 if there is none
+func (s *Session) Version() int {
+	if s.Selected > 0 && s.Selected <= len(s.Iterations) {
+		return s.Selected
+	}
+	return len(s.Iterations)
+}
+
+// Current returns the current candidate
+func (s *Session) Current() Iteration {
+	if len(s.Iterations) == 0 {
+		return Iteration{}
+	}
+	return s.Iterations[s.Version()-1]
+}
+
+// Parent returns the version the current candidate was refined from
+func (s *Session) Parent() (Iteration, bool) {
+	parent := s.Current().Parent
+	if parent <= 0 || parent > len(s.Iterations) {
+		return Iteration{}, false
+	}
+	return s.Iterations[parent-1], true
+}
+
+// SelectVersion makes an earlier or later version the current candidate
+func (s *Session) SelectVersion(version int) error {
+	if version < 1 || version > len(s.Iterations) {
+		return fmt.Errorf("version %d does not exist, choose 1-%d", version, len(s.Iterations))
+	}
+	s.Selected = version
+	if version == len(s.Iterations) {
+		s.Selected = 0
+	}
+	return nil
+}
+
+// RefinementContext returns the context for refining the current candidate in the provider session.
+// The current candidate is included when it is not the provider's last response, e.g. after an undo.
+func (s *Session) RefinementContext(feedback string) *RefinementContext {
+	refinement := &RefinementContext{
+		SessionID: s.LLMSessionID,
+		Feedback:  feedback,
+	}
+	if s.Version() != s.LLMVersion {
+		current := s.Current()
+		refinement.BaseTitle, refinement.BaseBody = current.Title, current.Body
+	}
+	return refinement
+}
+
+// HistoryStore saves sessions as JSON files in a directory
+type HistoryStore struct {
+	Dir   string
+	Limit int
+}
+
+// NewHistoryStore returns the history store in the config directory
+func NewHistoryStore(config *Config) *HistoryStore {
+	return &HistoryStore{
+		Dir:   filepath.Join(config.ConfigDir, "history"),
+		Limit: config.GetInt("history_limit", DefaultHistoryLimit),
+	}
+}
+
+// Save writes the session and prunes the oldest sessions beyond the limit
+func (h *HistoryStore) Save(session *Session) error {
+	if err := os.MkdirAll(h.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	session.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	if err := os.WriteFile(h.path(session.ID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+
+	return h.prune()
+}
+
+// Load reads a session by ID
+func (h *HistoryStore) Load(id string) (*Session, error) {
+	// IDs come from the command line, so only the generated format may reach the file system
+	if !sessionIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid session ID %q", id)
+	}
+	data, err := os.ReadFile(h.path(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("session %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", id, err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", id, err)
+	}
+	return &session, nil
+}
+
+// List returns all sessions, newest first
+func (h *HistoryStore) List() ([]*Session, error) {
+	ids, err := h.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*Session
+	for i := len(ids) - 1; i >= 0; i-- {
+		session, err := h.Load(ids[i])
+		if err != nil {
+			// Skip unreadable files rather than hiding the whole history
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// Latest returns the newest session for the repository and branch that has no PR yet
+func (h *HistoryStore) Latest(repoRoot, branch string) (*Session, error) {
+	sessions, err := h.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		if session.RepoRoot == repoRoot && session.Branch == branch && session.Status != SessionCreated {
+			return session, nil
+		}
+	}
+	return nil, fmt.Errorf("no unfinished session found for branch %s", branch)
+}
+
+// path returns the file a session is stored in
+func (h *HistoryStore) path(id string) string {
+	return filepath.Join(h.Dir, id+".json")
+}
+
+// ids returns the stored session IDs, oldest first
+func (h *HistoryStore) ids() ([]string, error) {
+	entries, err := os.ReadDir(h.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	// IDs start with a timestamp, so lexical order is chronological
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// prune removes the oldest sessions beyond the limit
+func (h *HistoryStore) prune() error {
+	if h.Limit <= 0 {
+		return nil
+	}
+
+	ids, err := h.ids()
+	if err != nil {
+		return err
+	}
+	for len(ids) > h.Limit {
+		if err := os.Remove(h.path(ids[0])); err != nil {
+			return fmt.Errorf("failed to prune history: %w", err)
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
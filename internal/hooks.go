@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// hookMarker identifies hook scripts written by prgen
+	hookMarker = "# Installed by prgen"
+	// chainedHookSuffix is appended to pre-existing hooks that prgen's hook calls first
+	chainedHookSuffix = ".prgen-chained"
+	// hookGuardEnv prevents the pre-push hook from running again for prgen's own push
+	hookGuardEnv = "PRGEN_HOOK_RUNNING"
+)
+
+// hookScripts maps hook names to the prgen command they run
+var hookScripts = map[string]string{
+	"prepare-commit-msg": `prgen commit --hook "$@"
+`,
+	// The PR can only be created once the branch is on the remote, so prgen runs in the
+	// background after the push process (the hook's parent) has exited.
+	"pre-push": `if [ -z "$` + hookGuardEnv + `" ]; then
+	push_pid=$PPID
+	log="$(git rev-parse --git-dir)/prgen-pre-push.log"
+	echo "prgen: creating the PR in the background once the push finishes (log: $log)"
+	(
+		while kill -0 "$push_pid" 2>/dev/null; do sleep 1; done
+		` + hookGuardEnv + `=1 prgen --yes
+	) >"$log" 2>&1 </dev/null &
+fi
+`,
+}
+
+// HookResult describes what happened to one hook during install or uninstall
+type HookResult struct {
+	Name    string
+	Path    string
+	Chained bool // A pre-existing hook is kept and called first
+	Skipped bool // Nothing to do, e.g. not installed by prgen
+}
+
+// GetHooksDir returns the repository's hooks directory, respecting core.hooksPath
+func GetHooksDir() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-path", "hooks")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find hooks directory (not a git repository?): %w", err)
+	}
+
+	dir, err := filepath.Abs(strings.TrimSpace(string(output)))
+	if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// InstallHooks writes prgen's prepare-commit-msg hook, and the pre-push hook if requested.
+// Existing hooks are renamed and called before prgen instead of being overwritten.
+func InstallHooks(prePush bool) ([]HookResult, error) {
+	hooksDir, err := GetHooksDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	names := []string{"prepare-commit-msg"}
+	if prePush {
+		names = append(names, "pre-push")
+	}
+
+	var results []HookResult
+	for _, name := range names {
+		result, err := installHook(hooksDir, name)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// installHook writes a single hook, moving an existing foreign hook aside for chaining
+func installHook(hooksDir, name string) (HookResult, error) {
+	path := filepath.Join(hooksDir, name)
+	chainedPath := path + chainedHookSuffix
+	result := HookResult{Name: name, Path: path}
+
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil && strings.Contains(string(existing), hookMarker):
+		// Already ours, rewrite it below so it picks up the current script
+	case err == nil:
+		if _, err := os.Stat(chainedPath); err == nil {
+			return result, fmt.Errorf("cannot chain %s: %s already exists", name, chainedPath)
+		}
+		if err := os.Rename(path, chainedPath); err != nil {
+			return result, fmt.Errorf("failed to move existing %s hook: %w", name, err)
+		}
+	case !os.IsNotExist(err):
+		return result, fmt.Errorf("failed to read %s hook: %w", name, err)
+	}
+
+	if _, err := os.Stat(chainedPath); err == nil {
+		result.Chained = true
+	}
+
+	if err := os.WriteFile(path, []byte(buildHookScript(name)), 0755); err != nil {
+		return result, fmt.Errorf("failed to write %s hook: %w", name, err)
+	}
+	return result, nil
+}
+
+// buildHookScript returns the shell script for a hook, calling any chained hook first
+func buildHookScript(name string) string {
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n")
+	script.WriteString(hookMarker + ". Remove with: prgen hooks uninstall\n\n")
+	script.WriteString(fmt.Sprintf("chained=\"$(dirname \"$0\")/%s%s\"\n", name, chainedHookSuffix))
+
+	if name == "pre-push" {
+		// pre-push receives the refs on stdin, which the chained hook needs as well
+		script.WriteString("input=$(cat)\n")
+		script.WriteString("if [ -x \"$chained\" ]; then\n")
+		script.WriteString("\tprintf '%s\\n' \"$input\" | \"$chained\" \"$@\" || exit $?\n")
+		script.WriteString("fi\n\n")
+		script.WriteString("# Create the PR for this branch if there is none yet; never block the push\n")
+	} else {
+		script.WriteString("if [ -x \"$chained\" ]; then\n")
+		script.WriteString("\t\"$chained\" \"$@\" || exit $?\n")
+		script.WriteString("fi\n\n")
+	}
+
+	script.WriteString(hookScripts[name])
+	return script.String()
+}
+
+// UninstallHooks removes prgen's hooks and restores any chained hooks
+func UninstallHooks() ([]HookResult, error) {
+	hooksDir, err := GetHooksDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []HookResult
+	for _, name := range []string{"prepare-commit-msg", "pre-push"} {
+		path := filepath.Join(hooksDir, name)
+		chainedPath := path + chainedHookSuffix
+		result := HookResult{Name: name, Path: path}
+
+		existing, err := os.ReadFile(path)
+		if os.IsNotExist(err) || (err == nil && !strings.Contains(string(existing), hookMarker)) {
+			result.Skipped = true
+			results = append(results, result)
+			continue
+		}
+		if err != nil {
+			return results, fmt.Errorf("failed to read %s hook: %w", name, err)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return results, fmt.Errorf("failed to remove %s hook: %w", name, err)
+		}
+		if _, err := os.Stat(chainedPath); err == nil {
+			if err := os.Rename(chainedPath, path); err != nil {
+				return results, fmt.Errorf("failed to restore chained %s hook: %w", name, err)
+			}
+			result.Chained = true
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,330 @@
+package internal
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultDiffContextLines is the number of unchanged lines kept around each change
+const DefaultDiffContextLines = 3
+
+// definitionPoints is added to a hunk's significance for each definition it changes
+const definitionPoints = 10
+
+// HunkKind tells whether a hunk changes code or only its formatting or comments
+type HunkKind string
+
+const (
+	HunkCode       HunkKind = "code"
+	HunkWhitespace HunkKind = "whitespace-only"
+	HunkComment    HunkKind = "comment-only"
+)
+
+// diffHunk is one "@@" section of a file diff
+type diffHunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Section            string // Text after the closing "@@", usually the enclosing function
+	Lines              []string
+	Added, Removed     int
+	Kind               HunkKind
+	Significance       int
+	parsed             bool // False when the header could not be parsed, e.g. combined diffs
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@(.*)$`)
+
+// splitHunks splits a file diff into its header lines and hunks
+func splitHunks(content string) ([]string, []diffHunk) {
+	var header []string
+	var hunks []diffHunk
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			hunks = append(hunks, parseHunkHeader(line))
+			continue
+		}
+		if len(hunks) == 0 {
+			header = append(header, line)
+			continue
+		}
+
+		hunk := &hunks[len(hunks)-1]
+		hunk.Lines = append(hunk.Lines, line)
+		switch {
+		case strings.HasPrefix(line, "+"):
+			hunk.Added++
+		case strings.HasPrefix(line, "-"):
+			hunk.Removed++
+		}
+	}
+
+	// The diff's trailing newline leaves an empty last line
+	if n := len(hunks); n > 0 {
+		lines := hunks[n-1].Lines
+		if len(lines) > 0 && lines[len(lines)-1] == "" {
+			hunks[n-1].Lines = lines[:len(lines)-1]
+		}
+	}
+	return header, hunks
+}
+
+// parseHunkHeader reads the line ranges of an "@@ -a,b +c,d @@" header
+func parseHunkHeader(line string) diffHunk {
+	match := hunkHeaderPattern.FindStringSubmatch(line)
+	if match == nil {
+		return diffHunk{Section: line}
+	}
+
+	count := func(value string) int {
+		if value == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(value)
+		return n
+	}
+	oldStart, _ := strconv.Atoi(match[1])
+	newStart, _ := strconv.Atoi(match[3])
+	return diffHunk{
+		OldStart: oldStart,
+		OldLines: count(match[2]),
+		NewStart: newStart,
+		NewLines: count(match[4]),
+		Section:  match[5],
+		parsed:   true,
+	}
+}
+
+// Header renders the hunk's "@@" line
+func (h diffHunk) Header() string {
+	if !h.parsed {
+		return h.Section
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@%s", h.OldStart, h.OldLines, h.NewStart, h.NewLines, h.Section)
+}
+
+// Render returns the hunk as diff text
+func (h diffHunk) Render() string {
+	return h.Header() + "\n" + strings.Join(h.Lines, "\n") + "\n"
+}
+
+// RenderTruncated returns the hunk cut to its first maxLines lines
+func (h diffHunk) RenderTruncated(maxLines int) string {
+	if len(h.Lines) <= maxLines {
+		return h.Render()
+	}
+	return h.Header() + "\n" + strings.Join(h.Lines[:maxLines], "\n") +
+		fmt.Sprintf("\n# ... (hunk truncated: +%d -%d lines) ...\n", h.Added, h.Removed)
+}
+
+// trimContext keeps at most contextLines unchanged lines around each change,
+// splitting the hunk where longer runs of unchanged lines are dropped
+func trimContext(h diffHunk, contextLines int) []diffHunk {
+	if !h.parsed || contextLines < 0 {
+		return []diffHunk{h}
+	}
+
+	// Line numbers on both sides before each line
+	oldAt := make([]int, len(h.Lines))
+	newAt := make([]int, len(h.Lines))
+	keep := make([]bool, len(h.Lines))
+	oldLine, newLine := h.OldStart, h.NewStart
+	for i, line := range h.Lines {
+		oldAt[i], newAt[i] = oldLine, newLine
+		switch {
+		case strings.HasPrefix(line, "+"):
+			newLine++
+		case strings.HasPrefix(line, "-"):
+			oldLine++
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" belongs to the line before it
+		default:
+			oldLine++
+			newLine++
+		}
+
+		if isChangeLine(line) {
+			for j := max(0, i-contextLines); j <= min(len(h.Lines)-1, i+contextLines); j++ {
+				keep[j] = true
+			}
+		}
+	}
+	for i, line := range h.Lines {
+		if strings.HasPrefix(line, `\`) && i > 0 && keep[i-1] {
+			keep[i] = true
+		}
+	}
+
+	var result []diffHunk
+	for i := 0; i < len(h.Lines); {
+		if !keep[i] {
+			i++
+			continue
+		}
+
+		part := diffHunk{OldStart: oldAt[i], NewStart: newAt[i], Section: h.Section, parsed: true}
+		for ; i < len(h.Lines) && keep[i]; i++ {
+			line := h.Lines[i]
+			part.Lines = append(part.Lines, line)
+			switch {
+			case strings.HasPrefix(line, "+"):
+				part.Added++
+				part.NewLines++
+			case strings.HasPrefix(line, "-"):
+				part.Removed++
+				part.OldLines++
+			case strings.HasPrefix(line, `\`):
+			default:
+				part.OldLines++
+				part.NewLines++
+			}
+		}
+
+		// git numbers an empty side from the line before it
+		if part.OldLines == 0 {
+			part.OldStart--
+		}
+		if part.NewLines == 0 {
+			part.NewStart--
+		}
+		result = append(result, part)
+	}
+	return result
+}
+
+// isChangeLine reports whether a hunk line adds or removes content
+func isChangeLine(line string) bool {
+	return strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")
+}
+
+// classifyHunk decides whether a hunk only changes whitespace or comments, and rates its significance
+func classifyHunk(h *diffHunk, filePath string) {
+	// The old and new text of the hunk, line by line in order, so a line moved past
+	// a context line is a change even though the same lines are removed and added
+	var oldLines, newLines []string
+	commentOnly := true
+	prefixes := commentPrefixes(filePath)
+	definitions := 0
+
+	for _, line := range h.Lines {
+		if line == "" || line[0] == '\\' {
+			continue
+		}
+		text := stripWhitespace(line[1:])
+		switch line[0] {
+		case '-':
+			oldLines = appendNonEmpty(oldLines, text)
+		case '+':
+			newLines = appendNonEmpty(newLines, text)
+		default:
+			oldLines = appendNonEmpty(oldLines, text)
+			newLines = appendNonEmpty(newLines, text)
+		}
+		if !isChangeLine(line) {
+			continue
+		}
+
+		if trimmed := strings.TrimSpace(line[1:]); trimmed != "" && !hasCommentPrefix(trimmed, prefixes) {
+			commentOnly = false
+		}
+		if symbolDefinitionPattern.MatchString(line) {
+			definitions++
+		}
+	}
+
+	switch {
+	case h.Added+h.Removed == 0:
+		h.Kind = HunkCode
+	case !indentationMatters(filePath) && equalLines(oldLines, newLines):
+		h.Kind = HunkWhitespace
+	case commentOnly && len(prefixes) > 0:
+		h.Kind = HunkComment
+	default:
+		h.Kind = HunkCode
+	}
+	h.Significance = h.Added + h.Removed + definitions*definitionPoints
+}
+
+// stripWhitespace removes all whitespace from a line
+func stripWhitespace(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// appendNonEmpty appends text unless it is empty, so blank lines do not count as changes
+func appendNonEmpty(lines []string, text string) []string {
+	if text == "" {
+		return lines
+	}
+	return append(lines, text)
+}
+
+// equalLines reports whether two line sequences are identical
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// indentationMatters reports whether whitespace changes can change the meaning of the file,
+// e.g. Python blocks or YAML nesting
+func indentationMatters(filePath string) bool {
+	base := strings.ToLower(path.Base(filePath))
+	switch path.Ext(base) {
+	case ".py", ".pyi", ".yaml", ".yml", ".mk", ".coffee", ".haml", ".pug", ".jade", ".sass", ".styl",
+		".slim", ".nim", ".hs", ".elm", ".fs", ".fsx":
+		return true
+	}
+	return base == "makefile" || base == "gnumakefile"
+}
+
+// commentPrefixes returns the line comment markers of the file's language
+func commentPrefixes(filePath string) []string {
+	base := strings.ToLower(path.Base(filePath))
+	switch path.Ext(base) {
+	case ".go", ".js", ".jsx", ".ts", ".tsx", ".java", ".kt", ".rs", ".c", ".h", ".cc", ".cpp", ".hpp",
+		".cs", ".swift", ".scala", ".dart", ".vue", ".svelte":
+		return []string{"//", "/*", "*/", "* ", "*"}
+	case ".php":
+		return []string{"//", "#", "/*", "*/", "* ", "*"}
+	case ".py", ".rb", ".sh", ".yaml", ".yml", ".toml", ".ini", ".cfg", ".ex", ".exs", ".env":
+		return []string{"#"}
+	case ".sql", ".lua":
+		return []string{"--"}
+	}
+	if base == "dockerfile" || base == "makefile" {
+		return []string{"#"}
+	}
+	return nil
+}
+
+// hasCommentPrefix reports whether a trimmed line is a comment
+func hasCommentPrefix(line string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		// A lone "*" only counts as the continuation of a block comment
+		if prefix == "*" {
+			if line == "*" {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,342 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultIssueCacheTTL is how long fetched issue content is reused
+	DefaultIssueCacheTTL = 60 * time.Minute
+	// DefaultMaxIssueChars bounds the linked issue content added to the prompt
+	DefaultMaxIssueChars = 3000
+	// issueFetchTimeout is the timeout for a single HTTP issue fetch
+	issueFetchTimeout = 10 * time.Second
+)
+
+// LinkedIssue holds the fetched title and description of a linked issue
+type LinkedIssue struct {
+	Tracker   string    `json:"tracker"`
+	Key       string    `json:"key"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// IssueFetcher fetches issue content from GitHub or a tracker's HTTP endpoint and caches it on disk
+type IssueFetcher struct {
+	Client   *http.Client
+	CacheDir string
+	TTL      time.Duration
+}
+
+// NewIssueFetcher creates an IssueFetcher using the cache settings from the config
+func NewIssueFetcher(config *Config) *IssueFetcher {
+	ttl := DefaultIssueCacheTTL
+	if minutes := config.GetInt("issue_cache_ttl_minutes", -1); minutes >= 0 {
+		ttl = time.Duration(minutes) * time.Minute
+	}
+
+	return &IssueFetcher{
+		Client:   &http.Client{Timeout: issueFetchTimeout},
+		CacheDir: filepath.Join(config.ConfigDir, "cache", "issues"),
+		TTL:      ttl,
+	}
+}
+
+// FetchLinkedIssues fetches every issue reference whose tracker can be fetched.
+// Issues that fail to fetch are skipped and reported through the returned error.
+func FetchLinkedIssues(config *Config, refs []IssueRef) ([]LinkedIssue, error) {
+	if !config.GetBool("fetch_linked_issues", true) || len(refs) == 0 {
+		return nil, nil
+	}
+
+	trackers, err := LoadIssueTrackers(config)
+	if err != nil {
+		return nil, err
+	}
+	trackersByName := make(map[string]IssueTracker, len(trackers))
+	for _, tracker := range trackers {
+		trackersByName[tracker.Name] = tracker
+	}
+
+	fetcher := NewIssueFetcher(config)
+
+	var issues []LinkedIssue
+	var failures []string
+	for _, ref := range refs {
+		tracker, ok := trackersByName[ref.Tracker]
+		if !ok {
+			continue
+		}
+
+		issue, err := fetcher.Fetch(tracker, ref)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", ref.Key, err))
+			continue
+		}
+		if issue != nil {
+			issues = append(issues, *issue)
+		}
+	}
+
+	if len(failures) > 0 {
+		return issues, fmt.Errorf("could not fetch %s", strings.Join(failures, "; "))
+	}
+	return issues, nil
+}
+
+// Fetch returns the issue content for ref, using the cache when it is still fresh.
+// It returns nil if the tracker has no way to fetch issues.
+func (f *IssueFetcher) Fetch(tracker IssueTracker, ref IssueRef) (*LinkedIssue, error) {
+	if cached := f.readCache(ref); cached != nil {
+		return cached, nil
+	}
+
+	var issue *LinkedIssue
+	var err error
+	switch {
+	case tracker.FetchURL != "":
+		issue, err = f.fetchHTTP(tracker, ref)
+	case tracker.Name == "github" && isNumericKey(ref.Key):
+		issue, err = fetchGitHubIssue(ref)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	issue.FetchedAt = time.Now()
+	f.writeCache(ref, issue)
+	return issue, nil
+}
+
+// fetchHTTP requests the tracker's configured endpoint and extracts the title and body fields
+func (f *IssueFetcher) fetchHTTP(tracker IssueTracker, ref IssueRef) (*LinkedIssue, error) {
+	// Keys come from branch names and commits, so they are escaped before substitution
+	endpoint := strings.ReplaceAll(tracker.FetchURL, "{id}", url.PathEscape(ref.Key))
+
+	method := tracker.FetchMethod
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var requestBody io.Reader
+	if tracker.FetchBody != "" {
+		requestBody = strings.NewReader(strings.ReplaceAll(tracker.FetchBody, "{id}", jsonStringContent(ref.Key)))
+	}
+
+	req, err := http.NewRequest(method, endpoint, requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if requestBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range tracker.FetchHeaders {
+		// Allow secrets to be referenced as $ENV_VAR instead of stored in the config
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	titleField, bodyField := tracker.TitleField, tracker.BodyField
+	if titleField == "" {
+		titleField = "title"
+	}
+	if bodyField == "" {
+		bodyField = "body"
+	}
+
+	return &LinkedIssue{
+		Tracker: ref.Tracker,
+		Key:     ref.Key,
+		Title:   lookupJSONField(payload, titleField),
+		Body:    lookupJSONField(payload, bodyField),
+	}, nil
+}
+
+// jsonStringContent escapes value for use inside a quoted JSON string
+func jsonStringContent(value string) string {
+	data, _ := json.Marshal(value)
+	return string(data[1 : len(data)-1])
+}
+
+// fetchGitHubIssue reads a GitHub issue through gh CLI
+func fetchGitHubIssue(ref IssueRef) (*LinkedIssue, error) {
+	if err := checkGHCLI(); err != nil {
+		return nil, err
+	}
+
+	// Prefer the full URL so the issue is read from the base repository, not a fork
+	issueArg := ref.Key
+	if ref.URL != "" {
+		issueArg = ref.URL
+	}
+
+	cmd := exec.Command("gh", "issue", "view", issueArg, "--json", "title,body")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("gh issue view failed: %s", strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to execute gh issue view: %w", err)
+	}
+
+	var issue struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+	if err := json.Unmarshal(output, &issue); err != nil {
+		return nil, fmt.Errorf("failed to parse gh issue view output: %w", err)
+	}
+
+	return &LinkedIssue{
+		Tracker: ref.Tracker,
+		Key:     ref.Key,
+		Title:   issue.Title,
+		Body:    issue.Body,
+	}, nil
+}
+
+// lookupJSONField follows a dotted path such as "fields.summary" into decoded JSON.
+// Non-string values are re-encoded as JSON so nothing is silently lost.
+func lookupJSONField(payload interface{}, path string) string {
+	current := payload
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		current = object[part]
+	}
+
+	switch value := current.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+// cachePath returns the cache file for an issue reference
+func (f *IssueFetcher) cachePath(ref IssueRef) string {
+	// The URL distinguishes equal issue numbers in different repositories
+	hash := sha256.Sum256([]byte(ref.Tracker + ":" + ref.Key + ":" + ref.URL))
+	return filepath.Join(f.CacheDir, hex.EncodeToString(hash[:8])+".json")
+}
+
+// readCache returns the cached issue if it exists and has not expired
+func (f *IssueFetcher) readCache(ref IssueRef) *LinkedIssue {
+	if f.CacheDir == "" || f.TTL <= 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(f.cachePath(ref))
+	if err != nil {
+		return nil
+	}
+
+	var issue LinkedIssue
+	if err := json.Unmarshal(data, &issue); err != nil {
+		return nil
+	}
+	if time.Since(issue.FetchedAt) > f.TTL {
+		return nil
+	}
+	return &issue
+}
+
+// writeCache stores the issue on disk; failures only cost a refetch, so they are ignored
+func (f *IssueFetcher) writeCache(ref IssueRef, issue *LinkedIssue) {
+	if f.CacheDir == "" || f.TTL <= 0 {
+		return
+	}
+	if err := os.MkdirAll(f.CacheDir, 0755); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(issue)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(f.cachePath(ref), data, 0644)
+}
+
+// BuildLinkedIssueSection formats the fetched issues for the prompt, bounded to maxChars
+func BuildLinkedIssueSection(issues []LinkedIssue, maxChars int) string {
+	var result strings.Builder
+
+	for _, issue := range issues {
+		var entry bytes.Buffer
+		entry.WriteString(fmt.Sprintf("[%s] %s\n", issue.Key, strings.TrimSpace(issue.Title)))
+		if body := strings.TrimSpace(issue.Body); body != "" {
+			entry.WriteString(body + "\n")
+		}
+		entry.WriteString("\n")
+
+		remaining := maxChars - result.Len()
+		if remaining <= 0 {
+			break
+		}
+		if entry.Len() > remaining {
+			result.WriteString(truncateRunes(entry.String(), remaining))
+			result.WriteString("\n# ... (issue content truncated) ...\n")
+			break
+		}
+		result.Write(entry.Bytes())
+	}
+
+	return strings.TrimSpace(result.String())
+}
+
+// truncateRunes cuts text to at most maxBytes without splitting a multi-byte character
+func truncateRunes(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	cut := 0
+	for i := range text {
+		if i > maxBytes {
+			break
+		}
+		cut = i
+	}
+	return text[:cut]
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// newFakeTracker starts a tracker API that serves issues under /issue/{id} and counts requests
+func newFakeTracker(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestFetchHTTPExtractsNestedFields(t *testing.T) {
+	server, _ := newFakeTracker(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/issue/PROJ-1" {
+			t.Errorf("unexpected path %q", r.URL.EscapedPath())
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization header = %q, want the expanded token", got)
+		}
+		w.Write([]byte(`{"fields": {"summary": "Login fails", "description": "Steps to reproduce"}}`))
+	})
+	t.Setenv("FAKE_TRACKER_TOKEN", "secret")
+
+	tracker := IssueTracker{
+		Name:         "jira",
+		FetchURL:     server.URL + "/issue/{id}",
+		FetchHeaders: map[string]string{"Authorization": "Bearer $FAKE_TRACKER_TOKEN"},
+		TitleField:   "fields.summary",
+		BodyField:    "fields.description",
+	}
+	fetcher := &IssueFetcher{Client: server.Client()}
+
+	issue, err := fetcher.Fetch(tracker, IssueRef{Tracker: "jira", Key: "PROJ-1"})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if issue.Title != "Login fails" || issue.Body != "Steps to reproduce" {
+		t.Errorf("got title %q and body %q", issue.Title, issue.Body)
+	}
+}
+
+func TestFetchHTTPEscapesKey(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]string
+	server, _ := newFakeTracker(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &gotBody); err != nil {
+			t.Errorf("request body is not valid JSON: %s", data)
+		}
+		w.Write([]byte(`{"title": "t", "body": "b"}`))
+	})
+
+	tracker := IssueTracker{
+		Name:        "custom",
+		FetchURL:    server.URL + "/issue/{id}",
+		FetchMethod: http.MethodPost,
+		FetchBody:   `{"id": "{id}"}`,
+	}
+	fetcher := &IssueFetcher{Client: server.Client()}
+
+	key := `a/../b"c`
+	if _, err := fetcher.Fetch(tracker, IssueRef{Tracker: "custom", Key: key}); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if gotPath != "/issue/a%2F..%2Fb%22c" {
+		t.Errorf("path = %q, want the key escaped as one segment", gotPath)
+	}
+	if gotBody["id"] != key {
+		t.Errorf("body id = %q, want %q", gotBody["id"], key)
+	}
+}
+
+func TestFetchHTTPReportsStatus(t *testing.T) {
+	server, _ := newFakeTracker(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
+	tracker := IssueTracker{Name: "jira", FetchURL: server.URL + "/issue/{id}"}
+	fetcher := &IssueFetcher{Client: server.Client()}
+
+	_, err := fetcher.Fetch(tracker, IssueRef{Tracker: "jira", Key: "PROJ-404"})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("err = %v, want the unexpected status", err)
+	}
+}
+
+func TestFetchUsesCacheUntilExpired(t *testing.T) {
+	server, requests := newFakeTracker(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"title": "Cached", "body": "b"}`))
+	})
+
+	tracker := IssueTracker{Name: "jira", FetchURL: server.URL + "/issue/{id}"}
+	ref := IssueRef{Tracker: "jira", Key: "PROJ-2"}
+	fetcher := &IssueFetcher{Client: server.Client(), CacheDir: t.TempDir(), TTL: time.Hour}
+
+	for i := 0; i < 2; i++ {
+		issue, err := fetcher.Fetch(tracker, ref)
+		if err != nil {
+			t.Fatalf("Fetch %d failed: %v", i, err)
+		}
+		if issue.Title != "Cached" {
+			t.Errorf("Fetch %d title = %q", i, issue.Title)
+		}
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("server got %d requests, want 1 with the cache", got)
+	}
+
+	// An expired entry is fetched again
+	fetcher.TTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, err := fetcher.Fetch(tracker, ref); err != nil {
+		t.Fatalf("Fetch after expiry failed: %v", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("server got %d requests, want 2 after expiry", got)
+	}
+}
+
+func TestFetchWithoutEndpointSkipsTracker(t *testing.T) {
+	fetcher := &IssueFetcher{Client: http.DefaultClient}
+	issue, err := fetcher.Fetch(IssueTracker{Name: "linear"}, IssueRef{Tracker: "linear", Key: "ENG-1"})
+	if err != nil || issue != nil {
+		t.Errorf("got %v, %v; want nothing fetched", issue, err)
+	}
+}
+
+func TestBuildLinkedIssueSectionTruncates(t *testing.T) {
+	issues := []LinkedIssue{
+		{Key: "1", Title: "First", Body: strings.Repeat("é", 50)},
+		{Key: "2", Title: "Second", Body: "never shown"},
+	}
+
+	section := BuildLinkedIssueSection(issues, 40)
+	if !strings.Contains(section, "[1] First") {
+		t.Errorf("section lacks the first issue:\n%s", section)
+	}
+	if !strings.Contains(section, "(issue content truncated)") {
+		t.Errorf("section lacks the truncation note:\n%s", section)
+	}
+	if strings.Contains(section, "Second") {
+		t.Errorf("section contains the issue past the limit:\n%s", section)
+	}
+	if !utf8.ValidString(section) {
+		t.Errorf("section was cut inside a multi-byte character")
+	}
+}
@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// issueSectionHeading is the heading of the issue link section appended to PR bodies
+const issueSectionHeading = "## Related Issues"
+
+// IssueTracker describes how to find and link issue keys for one tracker
+type IssueTracker struct {
+	Name        string `json:"name"`         // e.g. "github", "jira", "linear"
+	Pattern     string `json:"pattern"`      // Regex matching the key; the first non-empty capture group is used if present
+	URLTemplate string `json:"url_template"` // Link template; {id}, {owner} and {repo} are substituted
+	Closes      bool   `json:"closes"`       // Render as "Closes #id" so GitHub closes the issue on merge
+
+	// Optional HTTP endpoint for fetching the issue's title and description.
+	// GitHub issues are fetched through gh CLI when no endpoint is set.
+	FetchURL     string            `json:"fetch_url,omitempty"`     // {id} is substituted
+	FetchMethod  string            `json:"fetch_method,omitempty"`  // Defaults to GET
+	FetchBody    string            `json:"fetch_body,omitempty"`    // Request body, e.g. a GraphQL query; {id} is substituted
+	FetchHeaders map[string]string `json:"fetch_headers,omitempty"` // Values may reference environment variables as $NAME
+	TitleField   string            `json:"title_field,omitempty"`   // Dotted JSON path, defaults to "title"
+	BodyField    string            `json:"body_field,omitempty"`    // Dotted JSON path, defaults to "body"
+}
+
+// IssueRef is an issue key found in the branch name or commit trailers
+type IssueRef struct {
+	Tracker string
+	Key     string
+	URL     string
+	Closes  bool
+}
+
+// defaultIssueTrackers is used when issue_trackers is not set in the config.
+// Bare GitHub issue numbers are only taken from the start of the branch name, optionally after a
+// type prefix (e.g. "fix/456-bar"), and from explicit "gh-456", "issue-456" or "#456" forms,
+// so that dates and versions such as "release/2024-q1" are not linked.
+var defaultIssueTrackers = []IssueTracker{
+	{
+		Name:        "github",
+		Pattern:     `(?i)^(?:(?:feat|feature|fix|bugfix|bug|chore|docs|refactor|perf|test|issues?)/)?(?:gh-|issues?-|#)?(\d+)(?:[-_]|$)|(?:^|[/_-])(?:gh|issues?)-(\d+)(?:[-_/]|$)|(?:^|[\s,(])#(\d+)\b`,
+		URLTemplate: "https://github.com/{owner}/{repo}/issues/{id}",
+		Closes:      true,
+	},
+}
+
+// LoadIssueTrackers returns the configured issue trackers, or the defaults
+func LoadIssueTrackers(config *Config) ([]IssueTracker, error) {
+	var trackers []IssueTracker
+	found, err := config.Decode("issue_trackers", &trackers)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return defaultIssueTrackers, nil
+	}
+	return trackers, nil
+}
+
+// FindIssueRefs looks up issue keys in the current branch name and the trailers of its commits since base
+func FindIssueRefs(config *Config, base string) ([]IssueRef, error) {
+	trackers, err := LoadIssueTrackers(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(trackers) == 0 {
+		return nil, nil
+	}
+
+	branch, err := GetCurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+
+	trailers, err := GetCommitTrailers(base)
+	if err != nil {
+		return nil, err
+	}
+
+	// The owner and repo are only needed for templates that reference them.
+	// Issues live in the base repository, which differs from the push remote for forks.
+	owner, repo, _ := ResolveBaseRepo(config)
+
+	sources := append([]string{branch}, trailers...)
+	return ExtractIssueRefs(trackers, sources, owner, repo)
+}
+
+// ExtractIssueRefs applies each tracker's pattern to the sources and returns the unique matches
+func ExtractIssueRefs(trackers []IssueTracker, sources []string, owner, repo string) ([]IssueRef, error) {
+	var refs []IssueRef
+	seen := make(map[string]bool)
+
+	for _, tracker := range trackers {
+		pattern, err := regexp.Compile(tracker.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for issue tracker %s: %w", tracker.Name, err)
+		}
+
+		for _, source := range sources {
+			for _, match := range pattern.FindAllStringSubmatch(source, -1) {
+				key := match[0]
+				for _, group := range match[1:] {
+					if group != "" {
+						key = group
+						break
+					}
+				}
+
+				id := tracker.Name + ":" + key
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+
+				refs = append(refs, IssueRef{
+					Tracker: tracker.Name,
+					Key:     key,
+					URL:     expandIssueURL(tracker.URLTemplate, key, owner, repo),
+					Closes:  tracker.Closes,
+				})
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+// expandIssueURL fills in the placeholders of a tracker URL template
+func expandIssueURL(template, key, owner, repo string) string {
+	if template == "" {
+		return ""
+	}
+	if (strings.Contains(template, "{owner}") && owner == "") ||
+		(strings.Contains(template, "{repo}") && repo == "") {
+		return ""
+	}
+
+	replacer := strings.NewReplacer("{id}", key, "{owner}", owner, "{repo}", repo)
+	return replacer.Replace(template)
+}
+
+// BuildIssuePromptSection describes the linked issues for the generation prompt, followed by
+// the fetched content of those issues bounded to maxChars
+func BuildIssuePromptSection(refs []IssueRef, issues []LinkedIssue, maxChars int) string {
+	var lines []string
+	for _, ref := range refs {
+		line := fmt.Sprintf("- %s (%s)", ref.Key, ref.Tracker)
+		if ref.URL != "" {
+			line += ": " + ref.URL
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "A \""+strings.TrimPrefix(issueSectionHeading, "## ")+"\" section linking these issues is appended automatically, so do not add one yourself.")
+	if content := BuildLinkedIssueSection(issues, maxChars); content != "" {
+		lines = append(lines, "", "Issue content:", content)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AppendIssueSection adds the issue link section to the PR body unless it is already there
+func AppendIssueSection(body string, refs []IssueRef) string {
+	if len(refs) == 0 || strings.Contains(body, issueSectionHeading) {
+		return body
+	}
+
+	var section strings.Builder
+	section.WriteString(issueSectionHeading + "\n")
+	for _, ref := range refs {
+		switch {
+		case ref.Closes && isNumericKey(ref.Key):
+			section.WriteString(fmt.Sprintf("- Closes #%s\n", ref.Key))
+		case ref.URL != "":
+			section.WriteString(fmt.Sprintf("- [%s](%s)\n", ref.Key, ref.URL))
+		default:
+			section.WriteString(fmt.Sprintf("- %s\n", ref.Key))
+		}
+	}
+
+	return strings.TrimRight(body, "\n") + "\n\n" + strings.TrimRight(section.String(), "\n")
+}
+
+// isNumericKey reports whether key is a plain issue number such as "456"
+func isNumericKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
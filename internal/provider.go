@@ -4,25 +4,57 @@ package internal
 type RefinementContext struct {
 	SessionID string // Session ID for continuing the conversation
 	Feedback  string // User's feedback for refinement
+	BaseTitle string // Version to refine when it is not the last response, e.g. after an undo
+	BaseBody  string
+}
+
+// PromptSection is an additional block of facts included in the generation prompt
+type PromptSection struct {
+	Title   string `json:"title"` // Upper-case heading, e.g. "LINKED ISSUES"
+	Content string `json:"content"`
 }
 
 // Provider represents an AI provider interface
 type Provider interface {
-	GeneratePRContent(config *Config, diff, background string) (*PRGenerationResult, error)
-	RefinePRContent(config *Config, diff, background string, refinement *RefinementContext) (*PRGenerationResult, error)
+	GeneratePRContent(config *Config, diff, background string, sections []PromptSection) (*PRGenerationResult, error)
+	RefinePRContent(config *Config, diff, background string, sections []PromptSection, refinement *RefinementContext) (*PRGenerationResult, error)
+	GenerateCommitMessage(config *Config, diff string, refinement *RefinementContext) (*CommitGenerationResult, error)
+	ReviewDiff(config *Config, diff string) (*ReviewResult, error)
+	GenerateTitleCandidates(config *Config, refinement *RefinementContext, count int) (*TitleCandidatesResult, error)
+	InterpretBenchmarks(config *Config, table string) (string, error)
 }
 
 // ClaudeProvider implements the Provider interface for Claude Code CLI
 type ClaudeProvider struct{}
 
 // GeneratePRContent generates PR content using Claude Code CLI
-func (p *ClaudeProvider) GeneratePRContent(config *Config, diff, background string) (*PRGenerationResult, error) {
-	return GeneratePRContentWithClaude(config, diff, background, nil)
+func (p *ClaudeProvider) GeneratePRContent(config *Config, diff, background string, sections []PromptSection) (*PRGenerationResult, error) {
+	return GeneratePRContentWithClaude(config, diff, background, sections, nil)
 }
 
 // RefinePRContent refines PR content based on user feedback using Claude Code CLI
-func (p *ClaudeProvider) RefinePRContent(config *Config, diff, background string, refinement *RefinementContext) (*PRGenerationResult, error) {
-	return GeneratePRContentWithClaude(config, diff, background, refinement)
+func (p *ClaudeProvider) RefinePRContent(config *Config, diff, background string, sections []PromptSection, refinement *RefinementContext) (*PRGenerationResult, error) {
+	return GeneratePRContentWithClaude(config, diff, background, sections, refinement)
+}
+
+// GenerateCommitMessage generates or refines a commit message for the staged diff using Claude Code CLI
+func (p *ClaudeProvider) GenerateCommitMessage(config *Config, diff string, refinement *RefinementContext) (*CommitGenerationResult, error) {
+	return GenerateCommitMessageWithClaude(config, diff, refinement)
+}
+
+// ReviewDiff reviews the diff and returns structured findings using Claude Code CLI
+func (p *ClaudeProvider) ReviewDiff(config *Config, diff string) (*ReviewResult, error) {
+	return ReviewDiffWithClaude(config, diff)
+}
+
+// GenerateTitleCandidates suggests alternative titles for the current PR using Claude Code CLI
+func (p *ClaudeProvider) GenerateTitleCandidates(config *Config, refinement *RefinementContext, count int) (*TitleCandidatesResult, error) {
+	return GenerateTitleCandidatesWithClaude(config, refinement, count)
+}
+
+// InterpretBenchmarks summarizes a benchmark comparison table using Claude Code CLI
+func (p *ClaudeProvider) InterpretBenchmarks(config *Config, table string) (string, error) {
+	return InterpretBenchmarksWithClaude(config, table)
 }
 
 // GetProvider returns the Claude provider
@@ -31,21 +63,61 @@ func GetProvider(config *Config) (Provider, error) {
 }
 
 // GeneratePRContentWithProvider generates PR content using the Claude provider
-func GeneratePRContentWithProvider(config *Config, diff, background string) (*PRGenerationResult, error) {
+func GeneratePRContentWithProvider(config *Config, diff, background string, sections []PromptSection) (*PRGenerationResult, error) {
 	provider, err := GetProvider(config)
 	if err != nil {
 		return nil, err
 	}
 
-	return provider.GeneratePRContent(config, diff, background)
+	return provider.GeneratePRContent(config, diff, background, sections)
 }
 
 // RefinePRContentWithProvider refines PR content using the Claude provider
-func RefinePRContentWithProvider(config *Config, diff, background string, refinement *RefinementContext) (*PRGenerationResult, error) {
+func RefinePRContentWithProvider(config *Config, diff, background string, sections []PromptSection, refinement *RefinementContext) (*PRGenerationResult, error) {
 	provider, err := GetProvider(config)
 	if err != nil {
 		return nil, err
 	}
 
-	return provider.RefinePRContent(config, diff, background, refinement)
+	return provider.RefinePRContent(config, diff, background, sections, refinement)
+}
+
+// GenerateCommitMessageWithProvider generates a commit message using the Claude provider
+func GenerateCommitMessageWithProvider(config *Config, diff string) (*CommitGenerationResult, error) {
+	provider, err := GetProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.GenerateCommitMessage(config, diff, nil)
+}
+
+// RefineCommitMessageWithProvider refines a commit message using the Claude provider
+func RefineCommitMessageWithProvider(config *Config, diff string, refinement *RefinementContext) (*CommitGenerationResult, error) {
+	provider, err := GetProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.GenerateCommitMessage(config, diff, refinement)
+}
+
+// GenerateTitleCandidatesWithProvider suggests alternative titles using the Claude provider
+func GenerateTitleCandidatesWithProvider(config *Config, refinement *RefinementContext, count int) (*TitleCandidatesResult, error) {
+	provider, err := GetProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.GenerateTitleCandidates(config, refinement, count)
+}
+
+// InterpretBenchmarksWithProvider summarizes a benchmark comparison table using the Claude provider
+func InterpretBenchmarksWithProvider(config *Config, table string) (string, error) {
+	provider, err := GetProvider(config)
+	if err != nil {
+		return "", err
+	}
+
+	return provider.InterpretBenchmarks(config, table)
 }
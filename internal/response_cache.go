@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultResponseCacheTTL is how long provider responses are reused
+	DefaultResponseCacheTTL = 24 * time.Hour
+	// DefaultResponseCacheMaxMB bounds the size of the response cache directory
+	DefaultResponseCacheMaxMB = 50
+)
+
+var (
+	// responseCacheDisabled is set by --no-cache
+	responseCacheDisabled atomic.Bool
+	// responseCacheHits counts the provider calls answered from the cache since it was last reported
+	responseCacheHits atomic.Int64
+)
+
+// SetResponseCacheEnabled turns the provider response cache on or off for this process
+func SetResponseCacheEnabled(enabled bool) {
+	responseCacheDisabled.Store(!enabled)
+}
+
+// TakeResponseCacheHits returns the number of cache hits since the last call and resets the count
+func TakeResponseCacheHits() int {
+	return int(responseCacheHits.Swap(0))
+}
+
+// cachedResponse is a provider response stored on disk
+type cachedResponse struct {
+	Response  string    `json:"response"`
+	SessionID string    `json:"session_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ResponseCache stores provider responses on disk, keyed by prompt and provider settings
+type ResponseCache struct {
+	Dir      string
+	TTL      time.Duration
+	MaxBytes int64
+}
+
+// NewResponseCache creates a ResponseCache using the cache settings from the config.
+// It returns nil if the cache is disabled.
+func NewResponseCache(config *Config) *ResponseCache {
+	if responseCacheDisabled.Load() || !config.GetBool("response_cache", true) {
+		return nil
+	}
+
+	ttl := DefaultResponseCacheTTL
+	if hours := config.GetInt("response_cache_ttl_hours", -1); hours >= 0 {
+		ttl = time.Duration(hours) * time.Hour
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &ResponseCache{
+		Dir:      filepath.Join(config.ConfigDir, "cache", "responses"),
+		TTL:      ttl,
+		MaxBytes: int64(config.GetInt("response_cache_max_mb", DefaultResponseCacheMaxMB)) * 1024 * 1024,
+	}
+}
+
+// ResponseCacheKey hashes the final prompt together with the provider settings that affect the response
+func ResponseCacheKey(config *Config, prompt string) string {
+	hash := sha256.New()
+	for _, key := range []string{"llm_provider", "model", "temperature", "max_tokens"} {
+		fmt.Fprintf(hash, "%s=%v\n", key, config.MainConfig[key])
+	}
+	hash.Write([]byte(prompt))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get returns the cached response for key if it exists and has not expired
+func (c *ResponseCache) Get(key string) (*cachedResponse, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	if time.Since(cached.CreatedAt) > c.TTL {
+		return nil, false
+	}
+
+	responseCacheHits.Add(1)
+	return &cached, true
+}
+
+// Put stores a response and prunes the cache; failures only cost a fresh call, so they are ignored
+func (c *ResponseCache) Put(key, response, sessionID string) {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(cachedResponse{
+		Response:  response,
+		SessionID: sessionID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(c.path(key), data, 0644); err != nil {
+		return
+	}
+
+	c.prune()
+}
+
+// path returns the cache file for a key
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// prune removes expired entries, then the oldest entries until the cache fits in MaxBytes
+func (c *ResponseCache) prune() {
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return
+	}
+
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []cacheFile
+	var total int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(c.Dir, entry.Name())
+		if time.Since(info.ModTime()) > c.TTL {
+			_ = os.Remove(path)
+			continue
+		}
+		files = append(files, cacheFile{path, info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	if c.MaxBytes <= 0 || total <= c.MaxBytes {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, file := range files {
+		if total <= c.MaxBytes {
+			break
+		}
+		if os.Remove(file.path) == nil {
+			total -= file.size
+		}
+	}
+}
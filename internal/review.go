@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// reviewSectionHeading is the heading of the reviewer notes section appended to PR bodies
+const reviewSectionHeading = "## Reviewer Notes"
+
+// Review finding severities, from most to least important
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// ReviewFinding is a single issue reported by the AI review
+type ReviewFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"` // Line in the new version of the file, 0 if not line specific
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ReviewResult holds the findings of an AI review
+type ReviewResult struct {
+	Findings  []ReviewFinding
+	SessionID string
+}
+
+// RunReview reviews the current branch's changes and optionally posts the findings on its PR.
+// This is the entrypoint for the review subcommand.
+func RunReview(post bool) {
+	InitializeUI()
+
+	// Load configuration with spinner
+	var config *Config
+	err := RunSpinnerWithTask("Loading configuration", func() error {
+		var err error
+		config, err = LoadConfig()
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to load config", err)
+		return
+	}
+	base := ResolveCurrentBase(config)
+
+	// Get git diff with spinner
+	var diff string
+	err = RunSpinnerWithTask("Analyzing git changes", func() error {
+		var err error
+		diff, err = GetDiff(base.Branch)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to get diff", err)
+		return
+	}
+	ShowDiffInfo(len(diff))
+	if diff == "" {
+		return
+	}
+
+	var result *ReviewResult
+	err = RunSpinnerWithTask("Reviewing changes", func() error {
+		var err error
+		result, err = ReviewDiffWithProvider(config, diff)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to review changes", err)
+		return
+	}
+	ShowCacheHits()
+	ShowReviewFindings(result.Findings)
+	ShowRunUsage()
+
+	if !post || len(result.Findings) == 0 {
+		return
+	}
+
+	err = RunSpinnerWithTask("Posting review comments", func() error {
+		prURL, err := GetCurrentPRURL()
+		if err != nil {
+			return err
+		}
+		return PostReviewComments(prURL, result.Findings)
+	})
+	if err != nil {
+		ShowError("Failed to post review comments", err)
+	}
+}
+
+// ReviewDiffWithProvider reviews the diff using the Claude provider
+func ReviewDiffWithProvider(config *Config, diff string) (*ReviewResult, error) {
+	provider, err := GetProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.ReviewDiff(config, diff)
+}
+
+// buildReviewPrompt constructs the prompt asking for structured review findings
+func buildReviewPrompt(config *Config, diff string) string {
+	prompt := "Please review the following git diff.\n\n"
+
+	prompt += "REVIEW INSTRUCTIONS:\n"
+	prompt += config.ReviewInstructions + "\n\n"
+
+	prompt += "GIT DIFF:\n" + diff + "\n\n"
+
+	prompt += "Respond with a JSON array only, without code fences or commentary. Each element must have:\n"
+	prompt += `- "file": path of the file as shown in the diff` + "\n"
+	prompt += `- "line": line number in the new version of the file (0 if not line specific)` + "\n"
+	prompt += `- "severity": one of "error", "warning", "info"` + "\n"
+	prompt += `- "message": the finding` + "\n"
+	prompt += "Respond with [] if there are no findings."
+
+	return prompt
+}
+
+// parseReviewResponse extracts the findings from the model's JSON response
+func parseReviewResponse(response string) ([]ReviewFinding, error) {
+	text := strings.TrimSpace(response)
+
+	// Tolerate code fences or text around the array
+	start := strings.Index(text, "[")
+	end := strings.LastIndex(text, "]")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("no JSON array found in review response")
+	}
+
+	var findings []ReviewFinding
+	if err := json.Unmarshal([]byte(text[start:end+1]), &findings); err != nil {
+		return nil, fmt.Errorf("failed to parse review findings: %w", err)
+	}
+
+	for i := range findings {
+		findings[i].Severity = normalizeSeverity(findings[i].Severity)
+		if findings[i].Line < 0 {
+			findings[i].Line = 0
+		}
+	}
+	sortFindings(findings)
+
+	return findings, nil
+}
+
+// normalizeSeverity maps the model's severity onto the supported values
+func normalizeSeverity(severity string) string {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "error", "critical", "high", "blocker":
+		return SeverityError
+	case "warning", "warn", "medium":
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// severityRank orders severities with the most important first
+func severityRank(severity string) int {
+	switch severity {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// sortFindings orders findings by severity, then file and line
+func sortFindings(findings []ReviewFinding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if severityRank(a.Severity) != severityRank(b.Severity) {
+			return severityRank(a.Severity) < severityRank(b.Severity)
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+// formatFindingLocation returns "file:line", or just the file for findings without a line
+func formatFindingLocation(finding ReviewFinding) string {
+	if finding.Line > 0 {
+		return fmt.Sprintf("%s:%d", finding.File, finding.Line)
+	}
+	return finding.File
+}
+
+// AppendReviewSection adds the reviewer notes section to the PR body unless it is already there
+func AppendReviewSection(body string, findings []ReviewFinding) string {
+	if len(findings) == 0 || strings.Contains(body, reviewSectionHeading) {
+		return body
+	}
+
+	var section strings.Builder
+	section.WriteString(reviewSectionHeading + "\n")
+	section.WriteString("_Automated self-review of this diff._\n\n")
+	for _, finding := range findings {
+		section.WriteString(fmt.Sprintf("- **%s** `%s`: %s\n", finding.Severity, formatFindingLocation(finding), finding.Message))
+	}
+
+	return strings.TrimRight(body, "\n") + "\n\n" + strings.TrimRight(section.String(), "\n")
+}
+
+// reviewComment is a line comment in the GitHub pull request review API
+type reviewComment struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Side string `json:"side"`
+	Body string `json:"body"`
+}
+
+// PostReviewComments posts the findings as a review on the given PR.
+// Line findings become inline comments; the rest are listed in the review body.
+func PostReviewComments(prURL string, findings []ReviewFinding) error {
+	if err := checkGHCLI(); err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	owner, repo, number, err := parsePRURL(prURL)
+	if err != nil {
+		return err
+	}
+
+	var comments []reviewComment
+	var generalNotes, allNotes []string
+	for _, finding := range findings {
+		note := fmt.Sprintf("- **%s** `%s`: %s", finding.Severity, formatFindingLocation(finding), finding.Message)
+		allNotes = append(allNotes, note)
+
+		if finding.Line > 0 && finding.File != "" {
+			comments = append(comments, reviewComment{
+				Path: finding.File,
+				Line: finding.Line,
+				Side: "RIGHT",
+				Body: fmt.Sprintf("**%s**: %s", finding.Severity, finding.Message),
+			})
+		} else {
+			generalNotes = append(generalNotes, note)
+		}
+	}
+
+	endpoint := fmt.Sprintf("repos/%s/%s/pulls/%s/reviews", owner, repo, number)
+	err = postReview(endpoint, buildReviewBody(generalNotes), comments)
+	if err != nil && len(comments) > 0 {
+		// GitHub rejects the whole review if any line is outside the diff,
+		// so fall back to listing every finding in the review body
+		return postReview(endpoint, buildReviewBody(allNotes), nil)
+	}
+	return err
+}
+
+// buildReviewBody returns the summary text of a posted review
+func buildReviewBody(notes []string) string {
+	body := "Automated self-review by prgen."
+	if len(notes) > 0 {
+		body += "\n\n" + strings.Join(notes, "\n")
+	}
+	return body
+}
+
+// postReview submits a COMMENT review through the GitHub API
+func postReview(endpoint, body string, comments []reviewComment) error {
+	if comments == nil {
+		comments = []reviewComment{}
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"event":    "COMMENT",
+		"body":     body,
+		"comments": comments,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode review: %w", err)
+	}
+
+	cmd := exec.Command("gh", "api", "--method", "POST", endpoint, "--input", "-")
+	cmd.Stdin = strings.NewReader(string(payload))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to post review: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// parsePRURL extracts owner, repo and number from https://github.com/owner/repo/pull/123
+func parsePRURL(prURL string) (owner, repo, number string, err error) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(strings.TrimPrefix(prURL, "https://"), "http://"), "/"), "/")
+	if len(parts) < 5 || parts[3] != "pull" {
+		return "", "", "", fmt.Errorf("unrecognized PR URL %s", prURL)
+	}
+	return parts[1], parts[2], parts[4], nil
+}
+
+// GetCurrentPRURL returns the URL of the PR for the current branch
+func GetCurrentPRURL() (string, error) {
+	if err := checkGHCLI(); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("gh", "pr", "view", "--json", "url", "--jq", ".url")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("no PR found for the current branch: %s", strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return "", fmt.Errorf("failed to execute gh pr view: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxScopes is the number of scopes a PR can touch before splitting it is suggested
+const DefaultMaxScopes = 3
+
+// ScopeCount is a conventional-commit scope with the number of changed files in it
+type ScopeCount struct {
+	Name  string
+	Files int
+}
+
+// scopeRule maps files matching a glob to a scope
+type scopeRule struct {
+	Pattern string
+	Scope   string
+}
+
+// AffectedScopes returns the scopes of the changed files, most changed first.
+// Scopes come from the "scopes" config map of path globs to scope names, or are
+// detected from Go modules, npm workspaces and Cargo workspaces when it is empty.
+func AffectedScopes(config *Config, diff string) ([]ScopeCount, error) {
+	rules := configuredScopeRules(config)
+	if len(rules) == 0 && config.GetBool("detect_scopes", true) {
+		var err error
+		if rules, err = detectScopeRules(); err != nil {
+			return nil, err
+		}
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	files, err := parseDiffByFile(diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, file := range files {
+		for _, rule := range rules {
+			if matchGlob(rule.Pattern, file.Path) {
+				counts[rule.Scope]++
+				break
+			}
+		}
+	}
+
+	var scopes []ScopeCount
+	for name, count := range counts {
+		scopes = append(scopes, ScopeCount{Name: name, Files: count})
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if scopes[i].Files != scopes[j].Files {
+			return scopes[i].Files > scopes[j].Files
+		}
+		return scopes[i].Name < scopes[j].Name
+	})
+	return scopes, nil
+}
+
+// configuredScopeRules reads the "scopes" config map, most specific pattern first
+func configuredScopeRules(config *Config) []scopeRule {
+	var rules []scopeRule
+	for pattern, scope := range config.GetStringMap("scopes") {
+		rules = append(rules, scopeRule{Pattern: pattern, Scope: scope})
+	}
+	sortScopeRules(rules)
+	return rules
+}
+
+// sortScopeRules orders rules so that longer, more specific patterns are tried first
+func sortScopeRules(rules []scopeRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if len(rules[i].Pattern) != len(rules[j].Pattern) {
+			return len(rules[i].Pattern) > len(rules[j].Pattern)
+		}
+		return rules[i].Pattern < rules[j].Pattern
+	})
+}
+
+// detectScopeRules finds the packages of a monorepo and maps each package directory to a scope named after it
+func detectScopeRules() ([]scopeRule, error) {
+	root, err := GetRepoRoot()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find repository root: %w", err)
+	}
+
+	// -z keeps paths with special characters unquoted
+	output, err := exec.Command("git", "-C", root, "ls-files", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository files: %w", err)
+	}
+	tracked := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+
+	dirs := make(map[string]bool)
+	// Every nested Go module is a package
+	for _, file := range tracked {
+		if path.Base(file) == "go.mod" && path.Dir(file) != "." {
+			dirs[path.Dir(file)] = true
+		}
+	}
+	// npm and Cargo workspaces list their members as directory globs
+	for _, workspace := range []struct {
+		manifest string
+		members  func([]byte) []string
+	}{
+		{"package.json", npmWorkspaces},
+		{"Cargo.toml", cargoWorkspaceMembers},
+	} {
+		data, err := os.ReadFile(filepath.Join(root, workspace.manifest))
+		if err != nil {
+			continue
+		}
+		patterns := workspace.members(data)
+		for _, file := range tracked {
+			if path.Base(file) != workspace.manifest || path.Dir(file) == "." {
+				continue
+			}
+			for _, pattern := range patterns {
+				if matchGlob(strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/"), path.Dir(file)) {
+					dirs[path.Dir(file)] = true
+					break
+				}
+			}
+		}
+	}
+
+	// Packages are named after their directory, or by their full path when two share a name,
+	// e.g. apps/utils and libs/utils
+	named := make(map[string]int)
+	for dir := range dirs {
+		named[path.Base(dir)]++
+	}
+	var rules []scopeRule
+	for dir := range dirs {
+		scope := path.Base(dir)
+		if named[scope] > 1 {
+			scope = dir
+		}
+		rules = append(rules, scopeRule{Pattern: dir + "/**", Scope: scope})
+	}
+	sortScopeRules(rules)
+	return rules, nil
+}
+
+// npmWorkspaces reads the workspace globs of a root package.json, in either the array or the object form
+func npmWorkspaces(data []byte) []string {
+	var manifest struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil || len(manifest.Workspaces) == 0 {
+		return nil
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(manifest.Workspaces, &patterns); err == nil {
+		return patterns
+	}
+	var object struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(manifest.Workspaces, &object); err == nil {
+		return object.Packages
+	}
+	return nil
+}
+
+var (
+	cargoMembersPattern = regexp.MustCompile(`(?s)\[workspace\].*?\bmembers\s*=\s*\[(.*?)\]`)
+	quotedStringPattern = regexp.MustCompile(`"([^"]*)"`)
+)
+
+// cargoWorkspaceMembers reads the member globs of a root Cargo.toml
+func cargoWorkspaceMembers(data []byte) []string {
+	match := cargoMembersPattern.FindSubmatch(data)
+	if match == nil {
+		return nil
+	}
+
+	var members []string
+	for _, member := range quotedStringPattern.FindAllSubmatch(match[1], -1) {
+		members = append(members, string(member[1]))
+	}
+	return members
+}
+
+// BuildScopePromptSection lists the scopes allowed in the title
+func BuildScopePromptSection(scopes []ScopeCount) string {
+	var names, details []string
+	for _, scope := range scopes {
+		names = append(names, scope.Name)
+		details = append(details, fmt.Sprintf("- %s (%d changed file(s))", scope.Name, scope.Files))
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Use one of these scopes in the title: %s.\n", strings.Join(names, ", ")))
+	if len(scopes) > 1 {
+		result.WriteString("Pick the scope of the main change, or omit the scope if the change is spread evenly.\n")
+	}
+	result.WriteString("Changed files per scope:\n")
+	result.WriteString(strings.Join(details, "\n"))
+	return result.String()
+}
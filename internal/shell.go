@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// shellWaitDelay bounds how long output is still read after a timed out command was killed
+const shellWaitDelay = 5 * time.Second
+
+// runShellCommand runs command with sh in dir and returns its combined output.
+// After timeout the command and every process it started are killed. A command that
+// ran but failed returns an *exec.ExitError, a command that timed out another error.
+func runShellCommand(dir, command string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	// Commands such as go test start processes of their own that keep the output pipe open,
+	// so killing sh alone would not end the wait
+	killProcessGroupOnCancel(cmd)
+	cmd.WaitDelay = shellWaitDelay
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("timed out after %s", timeout)
+	}
+	return output, err
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package internal
+
+import "os/exec"
+
+// killProcessGroupOnCancel keeps the default of killing only cmd itself, since process
+// groups are not available; WaitDelay still bounds the wait for its output
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,527 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SplitGroup is one of the smaller PRs a branch is proposed to be split into
+type SplitGroup struct {
+	Name    string
+	Files   []FileChange
+	Commits []Commit // Commits whose changes all belong to this group
+	Title   string
+	Body    string
+	Branch  string // Stacked branch created for the group
+	PRURL   string
+}
+
+// Diff returns the part of the branch diff that belongs to the group
+func (g *SplitGroup) Diff() string {
+	var parts []string
+	for _, file := range g.Files {
+		parts = append(parts, strings.TrimRight(file.Content, "\n"))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// LinesChanged returns the number of lines added and removed in the group
+func (g *SplitGroup) LinesChanged() (added, removed int) {
+	for _, file := range g.Files {
+		added += file.LinesAdded
+		removed += file.LinesRemoved
+	}
+	return added, removed
+}
+
+// SplitPlan is a proposal to split a branch into stacked PRs, in merge order
+type SplitPlan struct {
+	MergeBase    string
+	Groups       []*SplitGroup
+	MixedCommits []Commit // Commits whose changes are divided between several groups
+}
+
+// commitFiles is a commit with the files it changed
+type commitFiles struct {
+	Commit
+	Files []string
+}
+
+// PlanSplit groups the changed files into clusters by scope or directory, merges clusters that
+// are usually changed in the same commits, and orders them so that code comes before its users.
+// The diff is the change of the current branch since base.
+func PlanSplit(config *Config, base, diff string) (*SplitPlan, error) {
+	files, err := parseChangedFiles(diff)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeBase, err := getMergeBase(base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	commits, err := getCommitFiles(mergeBase, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	rules := configuredScopeRules(config)
+	if len(rules) == 0 && config.GetBool("detect_scopes", true) {
+		if rules, err = detectScopeRules(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Start with one cluster per scope or directory
+	keyOf := make(map[string]string, len(files))
+	parent := make(map[string]string)
+	for _, file := range files {
+		key := splitKey(rules, file.Path)
+		keyOf[file.Path] = key
+		parent[key] = key
+	}
+	find := func(key string) string {
+		for parent[key] != key {
+			key = parent[key]
+		}
+		return key
+	}
+
+	// Merge clusters that are changed together in most of the commits touching them
+	touched := make(map[string]int)
+	together := make(map[[2]string]int)
+	for _, commit := range commits {
+		keys := commitKeys(commit, keyOf)
+		for i, a := range keys {
+			touched[a]++
+			for _, b := range keys[i+1:] {
+				together[[2]string{a, b}]++
+			}
+		}
+	}
+	pairs := make([][2]string, 0, len(together))
+	for pair := range together {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i][0]+"\x00"+pairs[i][1] < pairs[j][0]+"\x00"+pairs[j][1]
+	})
+	for _, pair := range pairs {
+		count := together[pair]
+		if count >= 2 && count*2 > min(touched[pair[0]], touched[pair[1]]) {
+			a, b := find(pair[0]), find(pair[1])
+			if a != b {
+				parent[b] = a
+			}
+		}
+	}
+
+	// Collect the files of each merged cluster
+	groupOf := make(map[string]*SplitGroup)
+	members := make(map[*SplitGroup][]string)
+	var groups []*SplitGroup
+	fileGroup := make([]*SplitGroup, len(files))
+	for i, file := range files {
+		key := keyOf[file.Path]
+		root := find(key)
+		group, ok := groupOf[root]
+		if !ok {
+			group = &SplitGroup{}
+			groupOf[root] = group
+			groups = append(groups, group)
+		}
+		if !containsString(members[group], key) {
+			members[group] = append(members[group], key)
+		}
+		group.Files = append(group.Files, file)
+		fileGroup[i] = group
+	}
+	for _, group := range groups {
+		sort.Strings(members[group])
+		group.Name = strings.Join(members[group], "+")
+	}
+
+	// Groups whose symbols are used by other groups are merged first
+	dependencyScore := make(map[*SplitGroup]int)
+	for i, users := range findSymbolUsers(files) {
+		for _, j := range users {
+			if fileGroup[i] != fileGroup[j] {
+				dependencyScore[fileGroup[i]]++
+				dependencyScore[fileGroup[j]]--
+			}
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if dependencyScore[groups[i]] != dependencyScore[groups[j]] {
+			return dependencyScore[groups[i]] > dependencyScore[groups[j]]
+		}
+		return groups[i].Name < groups[j].Name
+	})
+
+	plan := &SplitPlan{MergeBase: mergeBase, Groups: groups}
+	for _, commit := range commits {
+		touchedGroups := make(map[*SplitGroup]bool)
+		for _, key := range commitKeys(commit, keyOf) {
+			touchedGroups[groupOf[find(key)]] = true
+		}
+		switch len(touchedGroups) {
+		case 0:
+			// The commit's changes were reverted later on the branch
+		case 1:
+			for group := range touchedGroups {
+				group.Commits = append(group.Commits, commit.Commit)
+			}
+		default:
+			plan.MixedCommits = append(plan.MixedCommits, commit.Commit)
+		}
+	}
+	return plan, nil
+}
+
+// splitKey returns the initial cluster of a file: its scope, or its top one or two directories
+func splitKey(rules []scopeRule, filePath string) string {
+	for _, rule := range rules {
+		if matchGlob(rule.Pattern, filePath) {
+			return rule.Scope
+		}
+	}
+
+	dir := path.Dir(filePath)
+	if dir == "." {
+		return "root"
+	}
+	segments := strings.Split(dir, "/")
+	if len(segments) > 2 {
+		segments = segments[:2]
+	}
+	return strings.Join(segments, "/")
+}
+
+// commitKeys returns the sorted clusters of the commit's files that are part of the branch diff
+func commitKeys(commit commitFiles, keyOf map[string]string) []string {
+	var keys []string
+	for _, file := range commit.Files {
+		if key, ok := keyOf[file]; ok && !containsString(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// getCommitFiles returns the commits between from and to, oldest first, with the files each changed
+func getCommitFiles(from, to string) ([]commitFiles, error) {
+	cmd := exec.Command("git", "log", "--reverse", "--format=%x1e%H%x1f%s", "--name-only", from+".."+to)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git log %s..%s failed: %s", from, to, strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return nil, err
+	}
+
+	var commits []commitFiles
+	for _, record := range strings.Split(string(output), "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		fields := strings.SplitN(lines[0], "\x1f", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		commit := commitFiles{Commit: Commit{SHA: fields[0], Subject: fields[1]}}
+		for _, line := range lines[1:] {
+			if line = strings.TrimSpace(line); line != "" {
+				commit.Files = append(commit.Files, unquoteGitPath(line))
+			}
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// GenerateSplitContent generates a title and body for each group in parallel.
+// Groups that fail get a placeholder title; an error is returned only if all of them fail.
+func GenerateSplitContent(config *Config, plan *SplitPlan) error {
+	errs := make([]error, len(plan.Groups))
+
+	var wg sync.WaitGroup
+	for i, group := range plan.Groups {
+		wg.Add(1)
+		go func(i int, group *SplitGroup) {
+			defer wg.Done()
+			sections := []PromptSection{{Title: "STACKED PR", Content: describeSplitPart(plan, i)}}
+			result, err := GeneratePRContentWithProvider(config, group.Diff(), "", sections)
+			if err != nil {
+				errs[i] = err
+				group.Title = fmt.Sprintf("Part %d: %s", i+1, group.Name)
+				return
+			}
+			group.Title, group.Body = result.Title, result.Body
+		}(i, group)
+	}
+	wg.Wait()
+
+	var failures []string
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", plan.Groups[i].Name, err))
+		}
+	}
+	if len(failures) == len(plan.Groups) {
+		return fmt.Errorf("all parts failed: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// describeSplitPart tells the model which part of a split change it is describing
+func describeSplitPart(plan *SplitPlan, index int) string {
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("This PR is part %d of %d of a larger change that is split into stacked PRs, merged in order.\n", index+1, len(plan.Groups)))
+	result.WriteString("Describe only the changes in this part. All parts:\n")
+	for i, group := range plan.Groups {
+		result.WriteString(fmt.Sprintf("%d. %s (%d file(s))\n", i+1, group.Name, len(group.Files)))
+	}
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// CreateSplitBranches creates one branch per group, each based on the previous one, without
+// touching the working tree. The last branch has the same content as HEAD.
+func CreateSplitBranches(plan *SplitPlan, branch string) error {
+	parent := plan.MergeBase
+	for i, group := range plan.Groups {
+		name := fmt.Sprintf("%s-part-%d-%s", branch, i+1, branchSlug(group.Name))
+		message := fmt.Sprintf("%s\n\nSplit from %s.\n", group.Title, branch)
+
+		commit, err := commitSplitGroup(parent, group, message)
+		if err != nil {
+			return fmt.Errorf("failed to create the commit for %s: %w", group.Name, err)
+		}
+		if output, err := exec.Command("git", "branch", name, commit).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to create branch %s: %s", name, strings.TrimSpace(string(output)))
+		}
+
+		group.Branch = name
+		parent = commit
+	}
+	return nil
+}
+
+// commitSplitGroup creates a commit on top of parent with the group's files as they are at HEAD
+func commitSplitGroup(parent string, group *SplitGroup, message string) (string, error) {
+	index, err := os.CreateTemp("", "prgen-index-*")
+	if err != nil {
+		return "", err
+	}
+	index.Close()
+	// git refuses to read an empty index file, but creates a missing one
+	os.Remove(index.Name())
+	defer os.Remove(index.Name())
+
+	if _, err := gitWithIndex(index.Name(), "", "read-tree", parent); err != nil {
+		return "", err
+	}
+
+	var entries strings.Builder
+	var paths []string
+	for _, file := range group.Files {
+		if file.Status == StatusRenamed {
+			entries.WriteString(removeIndexEntry(file.OldPath))
+		}
+		if file.IsDeleted {
+			entries.WriteString(removeIndexEntry(file.Path))
+		} else {
+			paths = append(paths, file.Path)
+		}
+	}
+	if len(paths) > 0 {
+		// ls-tree output is valid --index-info input
+		tree, err := gitWithIndex(index.Name(), "", append([]string{"ls-tree", "-z", "HEAD", "--"}, paths...)...)
+		if err != nil {
+			return "", err
+		}
+		if listed := strings.Count(tree, "\x00"); listed < len(paths) {
+			return "", fmt.Errorf("HEAD is missing %d of the %d files of group %s", len(paths)-listed, len(paths), group.Name)
+		}
+		entries.WriteString(tree)
+	}
+	if _, err := gitWithIndex(index.Name(), entries.String(), "update-index", "-z", "--index-info"); err != nil {
+		return "", err
+	}
+
+	tree, err := gitWithIndex(index.Name(), "", "write-tree")
+	if err != nil {
+		return "", err
+	}
+	commit, err := gitWithIndex(index.Name(), message, "commit-tree", strings.TrimSpace(tree), "-p", parent, "-F", "-")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// removeIndexEntry returns the --index-info record that removes a path
+func removeIndexEntry(filePath string) string {
+	return "0 " + strings.Repeat("0", 40) + "\t" + filePath + "\x00"
+}
+
+// gitWithIndex runs a git command against a separate index file.
+// It runs from the repository root, since paths from the diff are relative to it.
+func gitWithIndex(indexFile, stdin string, args ...string) (string, error) {
+	root, err := GetRepoRoot()
+	if err != nil {
+		return "", fmt.Errorf("failed to find repository root: %w", err)
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "GIT_INDEX_FILE="+indexFile)
+	cmd.Stdin = strings.NewReader(stdin)
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+var branchSlugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// branchSlug turns a group name into a branch name component
+func branchSlug(name string) string {
+	return strings.Trim(branchSlugPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// SplitOptions holds the settings of a split analysis
+type SplitOptions struct {
+	Create bool // Create the stacked branches and draft PRs after confirmation
+}
+
+// SuggestSplit proposes splitting the current branch into smaller stacked PRs,
+// and optionally creates the branches and draft PRs
+func SuggestSplit(opts SplitOptions) {
+	run := startRun(RunOptions{PRState: string(PRStateDraft)})
+	if run == nil {
+		return
+	}
+	config, remotes, branch := run.config, run.remotes, run.branch
+
+	var diff string
+	err := RunSpinnerWithTask("Analyzing git changes", func() error {
+		var err error
+		diff, err = GetDiff(run.base.Branch)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to get diff", err)
+		return
+	}
+	ShowDiffInfo(len(diff))
+	if diff == "" {
+		return
+	}
+
+	var plan *SplitPlan
+	err = RunSpinnerWithTask("Grouping related changes", func() error {
+		var err error
+		plan, err = PlanSplit(config, run.base.Branch, diff)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to group changes", err)
+		return
+	}
+	if len(plan.Groups) < 2 {
+		fmt.Println(infoStyle.Render("ℹ️  All changes are related, there is no need to split this branch"))
+		return
+	}
+
+	err = RunSpinnerWithTask(fmt.Sprintf("Generating titles for %d parts", len(plan.Groups)), func() error {
+		return GenerateSplitContent(config, plan)
+	})
+	if err != nil {
+		ShowError("Failed to generate titles", err)
+		return
+	}
+	ShowCacheHits()
+	ShowSplitPlan(plan)
+	ShowRunUsage()
+
+	if !opts.Create {
+		fmt.Println(infoStyle.Render("ℹ️  Run 'prgen split --create' to create these as stacked branches with draft PRs"))
+		return
+	}
+	if remotes.IsFork() {
+		ShowError("Cannot create stacked PRs", fmt.Errorf("stacked PRs are based on each other's branches, which must be pushed to %s, not a fork", remotes.Base))
+		return
+	}
+	if !AskConfirmation(fmt.Sprintf("Create %d stacked branches and draft PRs?", len(plan.Groups))) {
+		return
+	}
+
+	err = RunSpinnerWithTask("Creating stacked branches", func() error {
+		return CreateSplitBranches(plan, branch)
+	})
+	if err != nil {
+		ShowError("Failed to create stacked branches", err)
+		return
+	}
+
+	// The first part is opened against the branch's own base, each later part against the one before
+	base := run.base.Branch
+	for i, group := range plan.Groups {
+		err = RunSpinnerWithTask(fmt.Sprintf("Pushing %s", group.Branch), func() error {
+			return PushBranch(remotes.Push, group.Branch)
+		})
+		if err != nil {
+			ShowError("Failed to push branch", err)
+			return
+		}
+
+		target, err := ResolvePRTarget(remotes, group.Branch)
+		if err != nil {
+			ShowError("Failed to resolve PR target", err)
+			return
+		}
+		target.Base = base
+
+		body := group.Body + "\n\n" + stackNote(plan, i)
+		err = RunSpinnerWithTask(fmt.Sprintf("Creating draft PR %d of %d", i+1, len(plan.Groups)), func() error {
+			var err error
+			group.PRURL, err = CreateGitHubPR(group.Title, body, target, run.prOptions)
+			return err
+		})
+		if err != nil {
+			ShowError("Failed to create PR", err)
+			return
+		}
+		base = group.Branch
+	}
+	ShowSplitResult(plan)
+}
+
+// stackNote tells reviewers where a PR sits in the stack
+func stackNote(plan *SplitPlan, index int) string {
+	note := fmt.Sprintf("Part %d of %d of a stacked change.", index+1, len(plan.Groups))
+	if index > 0 {
+		note += fmt.Sprintf(" Depends on %s and should be merged after it.", plan.Groups[index-1].PRURL)
+	}
+	return note
+}
@@ -0,0 +1,423 @@
+package internal
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// stackParentTrailer is the commit trailer naming the branch a branch is stacked on
+const stackParentTrailer = "Stack-Parent"
+
+// Markers around the stack section so it can be replaced when the stack changes
+const (
+	stackSectionStart   = "<!-- prgen:stack -->"
+	stackSectionEnd     = "<!-- /prgen:stack -->"
+	stackSectionHeading = "## Stack"
+)
+
+// maxStackDepth stops following parents in case of a cycle in the configured parents
+const maxStackDepth = 20
+
+// Sources of the base branch, shown to the user
+const (
+	BaseFromTrunk    = "trunk"
+	BaseFromConfig   = "config"
+	BaseFromTrailer  = "trailer"
+	BaseFromDetected = "detected"
+)
+
+// StackEntry is a branch in a stack of PRs, bottom first
+type StackEntry struct {
+	Branch string `json:"branch"`
+	PRURL  string `json:"pr_url,omitempty"`
+}
+
+// PRBase is what a branch is diffed against and its PR is opened against
+type PRBase struct {
+	Remote   string // Remote PRs are opened against, "" if the remotes could not be resolved
+	Trunk    string // The repository's main line, from the base_branch config
+	TrunkRef string // The trunk as last fetched from Remote, e.g. "origin/main", or Trunk if it never was
+	Branch   string // The trunk, or the parent branch of a stacked branch
+	Source   string // How Branch was found, one of the BaseFrom constants
+}
+
+// ResolveTrunk returns the trunk as the base. The trunk is read from the base remote when it was
+// fetched, so that a stale local trunk does not make merged branches look unmerged.
+func ResolveTrunk(config *Config) PRBase {
+	trunk := config.GetString("base_branch", DefaultBaseBranch)
+	base := PRBase{Trunk: trunk, TrunkRef: trunk, Branch: trunk, Source: BaseFromTrunk}
+	if remotes, err := ResolveRemotes(config); err == nil {
+		base.Remote = remotes.Base
+		if remoteBranchExists(base.Remote, trunk) {
+			base.TrunkRef = base.Remote + "/" + trunk
+		}
+	}
+	return base
+}
+
+// ResolveBase returns the base of branch: its parent branch when it is stacked, otherwise the trunk.
+// A parent that is not on the base remote cannot be a PR base, so the trunk is returned with an error.
+func ResolveBase(config *Config, branch string) (PRBase, error) {
+	base := ResolveTrunk(config)
+	if branch == "" || branch == base.Trunk {
+		return base, nil
+	}
+
+	parent, source, err := ResolveParentBranch(config, base, branch)
+	if err != nil {
+		return base, err
+	}
+	if source != BaseFromTrunk && !remoteBranchExists(base.Remote, parent) {
+		return base, fmt.Errorf("parent branch %s is not on the base remote, push it first", parent)
+	}
+	base.Branch, base.Source = parent, source
+	return base, nil
+}
+
+// remoteBranchExists reports whether the remote-tracking branch of branch on remote exists
+func remoteBranchExists(remote, branch string) bool {
+	if remote == "" {
+		return false
+	}
+	_, err := revParse("refs/remotes/" + remote + "/" + branch)
+	return err == nil
+}
+
+// ResolveCurrentBase returns the base of the checked out branch, falling back to the trunk
+func ResolveCurrentBase(config *Config) PRBase {
+	branch, _ := GetCurrentBranch()
+	base, err := ResolveBase(config, branch)
+	if err != nil {
+		ShowError("Failed to find the parent branch, using "+base.Trunk, err)
+	}
+	return base
+}
+
+// ResolveParentBranch returns the branch that branch is stacked on, or the trunk of base.
+// The parent comes from the stack_parents config map, a Stack-Parent commit trailer,
+// or, with detect_stacks, the local branch sharing the most recent history with it.
+func ResolveParentBranch(config *Config, base PRBase, branch string) (string, string, error) {
+	if parent := config.GetStringMap("stack_parents")[branch]; parent != "" {
+		return parent, BaseFromConfig, nil
+	}
+
+	parent, err := getStackParentTrailer(base.TrunkRef, branch)
+	if err != nil {
+		return "", "", err
+	}
+	if parent != "" {
+		return parent, BaseFromTrailer, nil
+	}
+
+	if config.GetBool("detect_stacks", false) {
+		parent, err := detectParentBranch(base, branch)
+		if err != nil {
+			return "", "", err
+		}
+		if parent != "" {
+			return parent, BaseFromDetected, nil
+		}
+	}
+	return base.Trunk, BaseFromTrunk, nil
+}
+
+// getStackParentTrailer returns the Stack-Parent trailer of the newest commit on branch after trunkRef that has one
+func getStackParentTrailer(trunkRef, branch string) (string, error) {
+	format := fmt.Sprintf("--format=%%(trailers:key=%s,valueonly,unfold)", stackParentTrailer)
+	output, err := exec.Command("git", "log", trunkRef+".."+branch, format).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit trailers of %s: %w", branch, err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if value := strings.TrimSpace(line); value != "" {
+			return value, nil
+		}
+	}
+	return "", nil
+}
+
+// stackCandidates returns the local branches with commits that are not on trunkRef
+func stackCandidates(trunkRef string) ([]string, error) {
+	output, err := exec.Command("git", "for-each-ref", "--format=%(refname:short)", "--no-merged", trunkRef, "refs/heads").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// detectParentBranch finds the local branch that branch was most recently forked from.
+// Branches whose tip is in branch's history are preferred; otherwise a branch that was
+// rewritten (e.g. rebased or amended) after branch forked from it is found from its reflog.
+func detectParentBranch(base PRBase, branch string) (string, error) {
+	tip, err := revParse(branch)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", branch, err)
+	}
+	candidates, err := stackCandidates(base.TrunkRef)
+	if err != nil {
+		return "", err
+	}
+
+	type match struct {
+		branch   string
+		ancestor bool // The candidate's tip is in branch's history
+		distance int  // Commits on branch after the fork point
+	}
+	var matches []match
+	for _, candidate := range candidates {
+		if candidate == branch || candidate == base.Trunk {
+			continue
+		}
+		candidateTip, err := revParse(candidate)
+		if err != nil || candidateTip == tip {
+			continue
+		}
+
+		forkPoint, ancestor := candidateTip, true
+		if !isAncestor(candidateTip, branch) {
+			// Without a rewrite, the fork point is the merge-base, which a sibling shares too
+			forkPoint, ancestor = getForkPoint(candidate, branch), false
+			if mergeBase, _ := getMergeBase(candidate, branch); forkPoint == "" || forkPoint == mergeBase {
+				continue
+			}
+		}
+		if isAncestor(forkPoint, base.TrunkRef) {
+			continue
+		}
+
+		distance, err := countCommits(forkPoint, branch)
+		if err != nil {
+			return "", err
+		}
+		matches = append(matches, match{branch: candidate, ancestor: ancestor, distance: distance})
+	}
+	if len(matches) == 0 {
+		return "", nil
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].ancestor != matches[j].ancestor {
+			return matches[i].ancestor
+		}
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].branch < matches[j].branch
+	})
+	return matches[0].branch, nil
+}
+
+// getForkPoint returns the commit where branch forked from an earlier version of parent, or ""
+func getForkPoint(parent, branch string) string {
+	output, err := exec.Command("git", "merge-base", "--fork-point", parent, branch).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// FindStack returns the branches of the stack branch belongs to, bottom first, excluding the trunk of base.
+// Branches stacked on branch follow it; when a branch has several children they are listed depth first.
+// Children are only looked for when branch itself is stacked, so unstacked branches stay cheap.
+func FindStack(config *Config, base PRBase, branch string) ([]string, error) {
+	stack := []string{branch}
+	seen := map[string]bool{branch: true}
+	for current := branch; len(stack) < maxStackDepth; {
+		parent, source, err := ResolveParentBranch(config, base, current)
+		if err != nil {
+			return nil, err
+		}
+		if source == BaseFromTrunk || seen[parent] {
+			break
+		}
+		stack = append([]string{parent}, stack...)
+		seen[parent] = true
+		current = parent
+	}
+
+	if len(stack) == 1 {
+		return stack, nil
+	}
+
+	// Children are found among the other branches that are not merged yet
+	others, err := stackCandidates(base.TrunkRef)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for _, candidate := range others {
+		if !seen[candidate] && candidate != base.Trunk {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Strings(candidates)
+
+	parents := make(map[string]string)
+	for _, candidate := range candidates {
+		parent, _, err := ResolveParentBranch(config, base, candidate)
+		if err != nil {
+			return nil, err
+		}
+		parents[candidate] = parent
+	}
+	var addChildren func(parent string)
+	addChildren = func(parent string) {
+		for _, candidate := range candidates {
+			if parents[candidate] == parent && !seen[candidate] && len(stack) < maxStackDepth {
+				seen[candidate] = true
+				stack = append(stack, candidate)
+				addChildren(candidate)
+			}
+		}
+	}
+	addChildren(branch)
+	return stack, nil
+}
+
+// FindStackPRs returns the stack of branch with the open PR of each branch, or nil if branch is not stacked
+func FindStackPRs(config *Config, remotes Remotes, base PRBase, branch string) ([]StackEntry, error) {
+	branches, err := FindStack(config, base, branch)
+	if err != nil || len(branches) < 2 {
+		return nil, err
+	}
+
+	entries := make([]StackEntry, len(branches))
+	for i, name := range branches {
+		entries[i].Branch = name
+		target, err := ResolvePRTarget(remotes, name)
+		if err != nil {
+			return nil, err
+		}
+		if entries[i].PRURL, err = FindOpenPR(target); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// BuildStackSection renders the stack navigation on trunk for the PR of the given branch
+func BuildStackSection(stack []StackEntry, trunk, branch string) string {
+	var section strings.Builder
+	section.WriteString(stackSectionStart + "\n")
+	section.WriteString(stackSectionHeading + "\n\n")
+	section.WriteString(fmt.Sprintf("This PR is part of a stack on `%s`, merged from the top of the list down:\n\n", trunk))
+	for i, entry := range stack {
+		switch {
+		case entry.Branch == branch:
+			section.WriteString(fmt.Sprintf("%d. 👉 **This PR** (`%s`)\n", i+1, entry.Branch))
+		case entry.PRURL != "":
+			section.WriteString(fmt.Sprintf("%d. %s (`%s`)\n", i+1, entry.PRURL, entry.Branch))
+		default:
+			section.WriteString(fmt.Sprintf("%d. `%s` (no PR yet)\n", i+1, entry.Branch))
+		}
+	}
+	section.WriteString(stackSectionEnd)
+	return section.String()
+}
+
+// ReplaceStackSection puts the stack section into the body, replacing an earlier version of it
+func ReplaceStackSection(body, section string) string {
+	start := strings.Index(body, stackSectionStart)
+	end := strings.Index(body, stackSectionEnd)
+	if start != -1 && end > start {
+		return body[:start] + section + body[end+len(stackSectionEnd):]
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + section
+}
+
+// AppendStackSection adds the stack navigation on trunk to the PR body of branch
+func AppendStackSection(body string, stack []StackEntry, trunk, branch string) string {
+	if len(stack) < 2 {
+		return body
+	}
+	return ReplaceStackSection(body, BuildStackSection(stack, trunk, branch))
+}
+
+// UpdateStackPRs rewrites the stack section of every open PR in the stack except the one of branch.
+// It returns the number of PRs updated; PRs that fail are reported in the error.
+func UpdateStackPRs(stack []StackEntry, trunk, branch string) (int, error) {
+	updated := 0
+	var failures []string
+	for _, entry := range stack {
+		if entry.Branch == branch || entry.PRURL == "" {
+			continue
+		}
+		body, err := GetPRBody(entry.PRURL)
+		if err == nil {
+			newBody := ReplaceStackSection(body, BuildStackSection(stack, trunk, entry.Branch))
+			if newBody == body {
+				continue
+			}
+			err = UpdatePRBody(entry.PRURL, newBody)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", entry.PRURL, err))
+			continue
+		}
+		updated++
+	}
+	if len(failures) > 0 {
+		return updated, fmt.Errorf("failed to update %s", strings.Join(failures, "; "))
+	}
+	return updated, nil
+}
+
+// ShowCurrentStack lists the stack of the current branch and optionally rewrites the
+// stack section of all its PRs. This is the entrypoint for the stack subcommand.
+func ShowCurrentStack(update bool) {
+	InitializeUI()
+
+	config, err := LoadConfig()
+	if err != nil {
+		ShowError("Failed to load config", err)
+		return
+	}
+	remotes, err := ResolveRemotes(config)
+	if err != nil {
+		ShowError("Failed to resolve git remotes", err)
+		return
+	}
+	branch, err := ValidatePushBranch(ResolveTrunk(config).Trunk)
+	if err != nil {
+		ShowError("Cannot find the stack", err)
+		return
+	}
+	base, err := ResolveBase(config, branch)
+	if err != nil {
+		ShowError("Failed to find the parent branch", err)
+		return
+	}
+	ShowBaseBranch(base)
+
+	var stack []StackEntry
+	err = RunSpinnerWithTask("Looking for stacked branches", func() error {
+		var err error
+		stack, err = FindStackPRs(config, remotes, base, branch)
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to look up the stack", err)
+		return
+	}
+	if len(stack) == 0 {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("ℹ️  '%s' is not stacked on or under another branch", branch)))
+		return
+	}
+	ShowStack(stack, base, branch)
+
+	if !update {
+		return
+	}
+	var updated int
+	err = RunSpinnerWithTask("Updating stacked PRs", func() error {
+		var err error
+		updated, err = UpdateStackPRs(stack, base.Trunk, "")
+		return err
+	})
+	if err != nil {
+		ShowError("Failed to update some stacked PRs", err)
+	}
+	fmt.Println(infoStyle.Render(fmt.Sprintf("🥞 Updated the stack section of %d PR(s)", updated)))
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DiffSummarizer turns the before and after versions of a changed file into a structured summary.
+// Summaries replace raw truncation of large files, so they should be short and list what changed.
+type DiffSummarizer interface {
+	// Name identifies the summarizer in the summary header, e.g. "go"
+	Name() string
+	// Supports reports whether the summarizer understands the file at path
+	Supports(path string) bool
+	// Summarize describes the changes; before is nil for new files and after is nil for deleted ones
+	Summarize(path string, before, after []byte) (string, error)
+}
+
+// diffSummarizers holds the registered summarizers, checked in registration order
+var diffSummarizers []DiffSummarizer
+
+// RegisterDiffSummarizer adds a summarizer for another language or file type
+func RegisterDiffSummarizer(summarizer DiffSummarizer) {
+	diffSummarizers = append(diffSummarizers, summarizer)
+}
+
+func init() {
+	RegisterDiffSummarizer(&GoSummarizer{})
+}
+
+// summarizeFileChange returns a structured summary of the file change, or "" if no summarizer
+// supports the file or its versions could not be read or parsed
+func summarizeFileChange(file FileChange) string {
+	for _, summarizer := range diffSummarizers {
+		if !summarizer.Supports(file.Path) {
+			continue
+		}
+
+		before, err := readBlob(file.OldBlob)
+		if err != nil {
+			return ""
+		}
+		after, err := readBlob(file.NewBlob)
+		if err != nil {
+			// Diffs against the working tree name blobs that are not stored yet
+			if after, err = readWorkingTreeFile(file.Path); err != nil {
+				return ""
+			}
+		}
+
+		summary, err := summarizer.Summarize(file.Path, before, after)
+		if err != nil || strings.TrimSpace(summary) == "" {
+			return ""
+		}
+
+		var result strings.Builder
+		oldPath := file.Path
+		if file.OldPath != "" {
+			oldPath = file.OldPath
+		}
+		result.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", oldPath, file.Path))
+		for _, line := range renameHeaderLines(file.Content) {
+			result.WriteString(line + "\n")
+		}
+		result.WriteString(fmt.Sprintf("# Summary (%s) of a large change: +%d -%d lines\n",
+			summarizer.Name(), file.LinesAdded, file.LinesRemoved))
+		result.WriteString(strings.TrimRight(summary, "\n") + "\n")
+		return result.String()
+	}
+	return ""
+}
+
+// renameHeaderLines returns the similarity, rename and copy lines of a file diff's extended header
+func renameHeaderLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "@@ ") {
+			break
+		}
+		for _, prefix := range []string{"similarity index ", "dissimilarity index ", "rename from ", "rename to ", "copy from ", "copy to "} {
+			if strings.HasPrefix(line, prefix) {
+				lines = append(lines, line)
+				break
+			}
+		}
+	}
+	return lines
+}
+
+// readWorkingTreeFile reads the current version of a file relative to the repository root
+func readWorkingTreeFile(path string) ([]byte, error) {
+	root, err := GetRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(root, path))
+}
+
+// readBlob reads a file version by its blob hash from the diff's index line.
+// It returns nil for the all-zero hash of a missing side (new or deleted file).
+func readBlob(hash string) ([]byte, error) {
+	if strings.Trim(hash, "0") == "" {
+		return nil, nil
+	}
+
+	cmd := exec.Command("git", "cat-file", "blob", hash)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", hash, err)
+	}
+	return output, nil
+}
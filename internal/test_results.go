@@ -0,0 +1,350 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTestTimeout is how long the test command may run when test_timeout_minutes is not configured
+const DefaultTestTimeout = 10 * time.Minute
+
+// Values of the test_failure config key
+const (
+	TestFailureWarn   = "warn"
+	TestFailureRefuse = "refuse"
+)
+
+// maxFailedTestsListed caps the failing tests passed to the prompt
+const maxFailedTestsListed = 20
+
+// testOutputTailLines is how much output is kept when a failure cannot be attributed to a test
+const testOutputTailLines = 20
+
+// minCoverageDelta hides coverage changes too small to mention
+const minCoverageDelta = 0.1
+
+// totalCoverageKey is the coverage entry of the whole run, as reported by the test runner
+const totalCoverageKey = "total"
+
+// meanCoverageKey is the unweighted mean of the package coverages, used when the runner
+// reports no total: go test -json gives percentages but not statement counts
+const meanCoverageKey = "mean of packages"
+
+// TestReport is the parsed result of a test run
+type TestReport struct {
+	Command     string             `json:"command"`
+	Succeeded   bool               `json:"succeeded"`
+	Passed      int                `json:"passed"`
+	Failed      int                `json:"failed"`
+	Skipped     int                `json:"skipped"`
+	FailedTests []string           `json:"failed_tests,omitempty"`
+	Coverage    map[string]float64 `json:"coverage,omitempty"` // Percent per package, and "total" or "mean of packages"
+	OutputTail  string             `json:"output_tail,omitempty"`
+	Duration    time.Duration      `json:"duration"`
+}
+
+// CoverageDelta is a coverage change between the base branch and the current branch
+type CoverageDelta struct {
+	Name   string
+	Before float64
+	After  float64
+	IsNew  bool // Not measured on the base branch
+}
+
+// RunTests runs the configured test_command in dir and parses its output.
+// A failing test run is reported in the result; an error means the command could not be run.
+func RunTests(config *Config, dir string) (*TestReport, error) {
+	command := config.GetString("test_command", "")
+	if command == "" {
+		return nil, fmt.Errorf("no test_command configured")
+	}
+
+	timeout := DefaultTestTimeout
+	if minutes := config.GetInt("test_timeout_minutes", 0); minutes > 0 {
+		timeout = time.Duration(minutes) * time.Minute
+	}
+	start := time.Now()
+	output, err := runShellCommand(dir, command, timeout)
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return nil, fmt.Errorf("failed to run test command: %w", err)
+	}
+
+	report := parseTestOutput(output, err == nil)
+	report.Command = command
+	report.Duration = time.Since(start).Round(time.Second)
+	return report, nil
+}
+
+// RunBaseTests runs the tests on a commit of the base branch in a temporary worktree.
+// Results are cached per commit and command, since neither changes.
+func RunBaseTests(config *Config, rev string) (*TestReport, error) {
+	sha, err := revParse(rev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+
+	hash := sha256.Sum256([]byte(sha + "\x00" + config.GetString("test_command", "")))
+	cachePath := filepath.Join(config.ConfigDir, "cache", "tests", hex.EncodeToString(hash[:])+".json")
+	if data, err := os.ReadFile(cachePath); err == nil {
+		var report TestReport
+		if json.Unmarshal(data, &report) == nil {
+			return &report, nil
+		}
+	}
+
+	dir, cleanup, err := AddTemporaryWorktree(sha)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	report, err := RunTests(config, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed write only costs another run next time
+	if data, err := json.Marshal(report); err == nil && os.MkdirAll(filepath.Dir(cachePath), 0755) == nil {
+		_ = os.WriteFile(cachePath, data, 0644)
+	}
+	return report, nil
+}
+
+// goTestEvent is a line of `go test -json` output
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Output  string
+}
+
+var (
+	goCoveragePattern = regexp.MustCompile(`coverage: ([\d.]+)% of statements`)
+	// Summary lines of other test runners: Go without -json, pytest-cov and Istanbul (Jest, nyc)
+	totalCoveragePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?m)^TOTAL\s+.*?([\d.]+)%\s*$`),
+		regexp.MustCompile(`(?m)^All files\s*\|\s*([\d.]+)`),
+		regexp.MustCompile(`coverage: ([\d.]+)%`),
+	}
+	testCountPatterns = map[string]*regexp.Regexp{
+		"passed":  regexp.MustCompile(`(\d+) (?:passed|passing)`),
+		"failed":  regexp.MustCompile(`(\d+) (?:failed|failing)`),
+		"skipped": regexp.MustCompile(`(\d+) (?:skipped|pending)`),
+	}
+	goFailPattern = regexp.MustCompile(`(?m)^\s*--- FAIL: (\S+)`)
+	goTestPattern = regexp.MustCompile(`(?m)^\s*--- (PASS|FAIL|SKIP): `)
+)
+
+// parseTestOutput reads the results from `go test -json` events, or from the
+// summary lines of other test runners
+func parseTestOutput(output []byte, succeeded bool) *TestReport {
+	report := &TestReport{Succeeded: succeeded, Coverage: make(map[string]float64)}
+
+	failedPackages := make(map[string]bool)
+	packagesWithFailedTests := make(map[string]bool)
+	events := 0
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var event goTestEvent
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &event) != nil || event.Action == "" {
+			continue
+		}
+		events++
+
+		switch {
+		case event.Action == "output" && event.Test == "":
+			if match := goCoveragePattern.FindStringSubmatch(event.Output); match != nil {
+				report.Coverage[event.Package], _ = strconv.ParseFloat(match[1], 64)
+			}
+		case event.Test == "" && event.Action == "fail":
+			failedPackages[event.Package] = true
+		case event.Test == "":
+		case event.Action == "fail":
+			report.FailedTests = append(report.FailedTests, event.Package+"."+event.Test)
+			packagesWithFailedTests[event.Package] = true
+			// Subtests are listed but only top-level tests are counted
+			if !strings.Contains(event.Test, "/") {
+				report.Failed++
+			}
+		case event.Action == "pass" && !strings.Contains(event.Test, "/"):
+			report.Passed++
+		case event.Action == "skip" && !strings.Contains(event.Test, "/"):
+			report.Skipped++
+		}
+	}
+
+	if events > 0 {
+		// Packages that failed without a failing test did not build
+		for pkg := range failedPackages {
+			if !packagesWithFailedTests[pkg] {
+				report.FailedTests = append(report.FailedTests, pkg+" (build failed)")
+			}
+		}
+		if len(report.Coverage) > 0 {
+			sum := 0.0
+			for _, percent := range report.Coverage {
+				sum += percent
+			}
+			report.Coverage[meanCoverageKey] = sum / float64(len(report.Coverage))
+		}
+	} else {
+		parseTestSummary(report, string(output))
+	}
+
+	sort.Strings(report.FailedTests)
+	if !succeeded && len(report.FailedTests) == 0 {
+		report.OutputTail = lastLines(string(output), testOutputTailLines)
+	}
+	return report
+}
+
+// parseTestSummary reads counts, failing Go tests and total coverage from plain test output
+func parseTestSummary(report *TestReport, output string) {
+	if matches := goTestPattern.FindAllStringSubmatch(output, -1); len(matches) > 0 {
+		for _, match := range matches {
+			switch match[1] {
+			case "PASS":
+				report.Passed++
+			case "FAIL":
+				report.Failed++
+			case "SKIP":
+				report.Skipped++
+			}
+		}
+		for _, match := range goFailPattern.FindAllStringSubmatch(output, -1) {
+			report.FailedTests = append(report.FailedTests, match[1])
+		}
+	} else {
+		counts := map[string]*int{"passed": &report.Passed, "failed": &report.Failed, "skipped": &report.Skipped}
+		for name, pattern := range testCountPatterns {
+			// The last summary wins, e.g. after a rerun
+			if matches := pattern.FindAllStringSubmatch(output, -1); len(matches) > 0 {
+				*counts[name], _ = strconv.Atoi(matches[len(matches)-1][1])
+			}
+		}
+	}
+
+	for _, pattern := range totalCoveragePatterns {
+		if matches := pattern.FindAllStringSubmatch(output, -1); len(matches) > 0 {
+			report.Coverage[totalCoverageKey], _ = strconv.ParseFloat(matches[len(matches)-1][1], 64)
+			break
+		}
+	}
+}
+
+// lastLines returns the last n lines of text
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isOverallCoverage reports whether a coverage entry covers the whole run rather than a package
+func isOverallCoverage(name string) bool {
+	return name == totalCoverageKey || name == meanCoverageKey
+}
+
+// OverallCoverage returns the coverage entry of the whole run: the total, or else the package mean
+func (r *TestReport) OverallCoverage() (name string, percent float64, ok bool) {
+	for _, name := range []string{totalCoverageKey, meanCoverageKey} {
+		if percent, ok := r.Coverage[name]; ok {
+			return name, percent, true
+		}
+	}
+	return "", 0, false
+}
+
+// CoverageDeltas compares coverage with the base branch: the overall coverage first, then changed packages by name
+func CoverageDeltas(report, base *TestReport) []CoverageDelta {
+	if report == nil || base == nil {
+		return nil
+	}
+
+	var deltas []CoverageDelta
+	for name, after := range report.Coverage {
+		before, measured := base.Coverage[name]
+		if measured && abs(after-before) < minCoverageDelta && !isOverallCoverage(name) {
+			continue
+		}
+		deltas = append(deltas, CoverageDelta{Name: name, Before: before, After: after, IsNew: !measured})
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if isOverallCoverage(deltas[i].Name) != isOverallCoverage(deltas[j].Name) {
+			return isOverallCoverage(deltas[i].Name)
+		}
+		return deltas[i].Name < deltas[j].Name
+	})
+	return deltas
+}
+
+// abs returns the absolute value of x
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Summary returns the counts of the run, e.g. "120 passed, 2 failed, 3 skipped"
+func (r *TestReport) Summary() string {
+	summary := fmt.Sprintf("%d passed, %d failed, %d skipped", r.Passed, r.Failed, r.Skipped)
+	if !r.Succeeded && r.Failed == 0 {
+		summary += ", command failed"
+	}
+	return summary
+}
+
+// BuildTestPromptSection states the test results and coverage changes as facts for the Testing section
+func BuildTestPromptSection(report, base *TestReport) string {
+	var result strings.Builder
+	result.WriteString("These results come from running the tests on this branch. Base the Testing section on them and do not claim any other testing.\n")
+	result.WriteString(fmt.Sprintf("Command: %s\n", report.Command))
+	result.WriteString(fmt.Sprintf("Result: %s\n", report.Summary()))
+
+	if len(report.FailedTests) > 0 {
+		result.WriteString("Failing tests:\n")
+		for i, test := range report.FailedTests {
+			if i == maxFailedTestsListed {
+				result.WriteString(fmt.Sprintf("- ... and %d more\n", len(report.FailedTests)-maxFailedTestsListed))
+				break
+			}
+			result.WriteString("- " + test + "\n")
+		}
+	} else if report.OutputTail != "" {
+		result.WriteString("End of the test output:\n" + report.OutputTail + "\n")
+	}
+
+	if deltas := CoverageDeltas(report, base); len(deltas) > 0 {
+		result.WriteString("Coverage compared with the base branch:\n")
+		for _, delta := range deltas {
+			result.WriteString("- " + delta.Describe() + "\n")
+		}
+	} else if name, percent, ok := report.OverallCoverage(); ok {
+		result.WriteString(fmt.Sprintf("Coverage (%s): %.1f%%\n", name, percent))
+	}
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// Describe returns the change, e.g. "pkg/api: 71.0% -> 74.5% (+3.5)"
+func (d CoverageDelta) Describe() string {
+	if d.IsNew {
+		return fmt.Sprintf("%s: %.1f%% (new)", d.Name, d.After)
+	}
+	return fmt.Sprintf("%s: %.1f%% -> %.1f%% (%+.1f)", d.Name, d.Before, d.After, d.After-d.Before)
+}
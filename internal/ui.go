@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -67,13 +68,29 @@ var (
 			BorderForeground(neutralColor).
 			Padding(0, 1).
 			Margin(0, 0)
+
+	insertedStyle = lipgloss.NewStyle().
+			Foreground(secondaryColor).
+			Underline(true)
+
+	deletedStyle = lipgloss.NewStyle().
+			Foreground(errorColor).
+			Strikethrough(true)
 )
 
+// nonInteractive disables spinners for runs without a terminal, e.g. from git hooks
+var nonInteractive bool
+
 // InitializeUI sets up Bubble Tea for beautiful output
 func InitializeUI() {
 	// Bubble Tea setup is handled per operation
 }
 
+// SetNonInteractive switches spinners to plain progress lines
+func SetNonInteractive(enabled bool) {
+	nonInteractive = enabled
+}
+
 // ShowStartupBanner displays a welcome banner
 func ShowStartupBanner() {
 	banner := lipgloss.NewStyle().
@@ -100,9 +117,50 @@ func ShowConfigSummary(config *Config) {
 		fmt.Sprintf("LLM Provider: %v", config.MainConfig["llm_provider"]),
 		fmt.Sprintf("Model: %v", config.MainConfig["model"]),
 	}
+	if config.RepoConfigPath != "" {
+		configData = append(configData, fmt.Sprintf("Repo Config: %s", config.RepoConfigPath))
+	}
 
 	content := strings.Join(configData, "\n")
 	fmt.Println(configTableStyle.Render(content))
+
+	if len(config.IgnoredRepoKeys) > 0 {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠️  Ignoring %s from %s, set them in config.json instead", strings.Join(config.IgnoredRepoKeys, ", "), repoConfigFile)))
+	}
+}
+
+// ShowRemotes displays the push and base remotes when they differ (fork workflow)
+func ShowRemotes(remotes Remotes) {
+	if !remotes.IsFork() {
+		return
+	}
+	fmt.Println(infoStyle.Render(fmt.Sprintf("ℹ️  Pushing to '%s', opening PR against '%s'", remotes.Push, remotes.Base)))
+}
+
+// ShowBaseBranch displays the parent branch of a stacked branch and how it was found
+func ShowBaseBranch(base PRBase) {
+	if base.Source == BaseFromTrunk {
+		return
+	}
+	fmt.Println(infoStyle.Render(fmt.Sprintf("🥞 Stacked on '%s' (%s), diffing and opening the PR against it", base.Branch, base.Source)))
+}
+
+// ShowStack lists the branches of a stack with their PRs, marking the current branch
+func ShowStack(stack []StackEntry, base PRBase, branch string) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("🥞 Stack on %s", base.Trunk)))
+	var lines []string
+	for i, entry := range stack {
+		name := entry.Branch
+		if name == branch {
+			name = configHeaderStyle.Render(name + " (current)")
+		}
+		pr := entry.PRURL
+		if pr == "" {
+			pr = "no PR yet"
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s  %s", i+1, name, pr))
+	}
+	fmt.Println(panelStyle.Render(strings.Join(lines, "\n")))
 }
 
 // ShowDiffInfo displays git diff information
@@ -115,6 +173,99 @@ func ShowDiffInfo(diffLength int) {
 	fmt.Println(infoStyle.Render(fmt.Sprintf("ℹ️  Found changes (%d characters)", diffLength)))
 }
 
+// ShowCacheHits reports provider responses that were reused from the cache since the last report
+func ShowCacheHits() {
+	hits := TakeResponseCacheHits()
+	if hits == 0 {
+		return
+	}
+	fmt.Println(infoStyle.Render(fmt.Sprintf("⚡ Reused %d cached response(s), run with --no-cache for a fresh one", hits)))
+}
+
+// usageShownCalls is the number of calls covered by the last usage summary
+var usageShownCalls int
+
+// ShowRunUsage displays the tokens and cost of this run so far, if there were calls since the last summary
+func ShowRunUsage() {
+	usage := RunUsage()
+	if usage.Calls == usageShownCalls {
+		return
+	}
+	usageShownCalls = usage.Calls
+	fmt.Println(infoStyle.Render(fmt.Sprintf("💰 Usage: %d call(s), %d input / %d output tokens, $%.4f",
+		usage.Calls, usage.InputTokens, usage.OutputTokens, usage.CostUSD)))
+}
+
+// ShowIssueRefs displays the issue keys that will be linked from the PR
+func ShowIssueRefs(refs []IssueRef) {
+	if len(refs) == 0 {
+		return
+	}
+
+	var keys []string
+	for _, ref := range refs {
+		keys = append(keys, ref.Key)
+	}
+	fmt.Println(infoStyle.Render(fmt.Sprintf("🔗 Linked issues: %s", strings.Join(keys, ", "))))
+}
+
+// ShowTestReport displays the test results and the change in total coverage
+func ShowTestReport(report, base *TestReport) {
+	style := successStyle
+	icon := "✅"
+	if !report.Succeeded {
+		style, icon = errorStyle, "❌"
+	}
+	fmt.Println(style.Render(fmt.Sprintf("%s Tests: %s in %s", icon, report.Summary(), report.Duration)))
+
+	for i, test := range report.FailedTests {
+		if i == maxFailedTestsListed {
+			fmt.Println(errorStyle.Render(fmt.Sprintf("   ... and %d more", len(report.FailedTests)-maxFailedTestsListed)))
+			break
+		}
+		fmt.Println(errorStyle.Render("   " + test))
+	}
+
+	for _, delta := range CoverageDeltas(report, base) {
+		if isOverallCoverage(delta.Name) {
+			fmt.Println(infoStyle.Render("📊 Coverage " + delta.Describe()))
+			return
+		}
+	}
+	if name, percent, ok := report.OverallCoverage(); ok {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("📊 Coverage (%s): %.1f%%", name, percent)))
+	}
+}
+
+// ShowBenchmark displays the benchmark comparison and warns when too few runs were made to find differences
+func ShowBenchmark(report *BenchmarkReport) {
+	significant := 0
+	for _, comparison := range report.Comparisons {
+		if comparison.Significant() {
+			significant++
+		}
+	}
+	fmt.Println(infoStyle.Render(fmt.Sprintf("⏱️  Benchmarks: %d significant change(s) in %d comparison(s)", significant, len(report.Comparisons))))
+	fmt.Println(report.Table)
+
+	if HasFewBenchmarkSamples(report.Comparisons) {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠️  Some benchmarks ran fewer than %d times, add -count to bench_command to detect differences", minBenchSamples)))
+	}
+}
+
+// ShowScopes displays the scopes touched by the change and suggests splitting when there are more than maxScopes
+func ShowScopes(scopes []ScopeCount, maxScopes int) {
+	var names []string
+	for _, scope := range scopes {
+		names = append(names, scope.Name)
+	}
+	fmt.Println(infoStyle.Render(fmt.Sprintf("🧭 Scopes: %s", strings.Join(names, ", "))))
+
+	if maxScopes > 0 && len(scopes) > maxScopes {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠️  This change touches %d scopes, consider splitting it with 'prgen split'", len(scopes))))
+	}
+}
+
 // ShowGeneratedContent displays the generated title and body in styled panels
 func ShowGeneratedContent(title, body string) {
 	// Title label and panel
@@ -152,6 +303,130 @@ func ShowGeneratedContent(title, body string) {
 	fmt.Println(vertical)
 }
 
+// ShowIterationDiff displays the word-level changes from an earlier version to the current one
+func ShowIterationDiff(fromVersion int, from, to Iteration) {
+	titleOps := WordDiff(from.Title, to.Title)
+	bodyOps := WordDiff(from.Body, to.Body)
+	if !HasChanges(titleOps) && !HasChanges(bodyOps) {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("ℹ️  No changes from version %d", fromVersion)))
+		return
+	}
+
+	fmt.Println(headerStyle.Render(fmt.Sprintf("🔀 Changes from version %d", fromVersion)))
+	content := "Title: " + renderDiffOps(titleOps) + "\n\n" + renderDiffOps(bodyOps)
+	fmt.Println(panelStyle.Render(content))
+}
+
+// renderDiffOps styles insertions and deletions line by line so the styles survive line breaks
+func renderDiffOps(ops []DiffOp) string {
+	var result strings.Builder
+	for _, op := range ops {
+		style := lipgloss.NewStyle()
+		switch op.Kind {
+		case DiffInsert:
+			style = insertedStyle
+		case DiffDelete:
+			style = deletedStyle
+		}
+
+		for i, line := range strings.Split(op.Text, "\n") {
+			if i > 0 {
+				result.WriteString("\n")
+			}
+			if line != "" {
+				result.WriteString(style.Render(line))
+			}
+		}
+	}
+	return result.String()
+}
+
+// ShowCommitMessage displays the generated commit message in a styled panel
+func ShowCommitMessage(message string) {
+	label := lipgloss.NewStyle().
+		Foreground(backgroundColor).
+		Background(primaryColor).
+		Bold(true).
+		Padding(0, 1).
+		Render("Commit Message")
+	panel := lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(primaryColor).
+		Padding(0, 1).
+		MarginTop(-1).
+		Render(message)
+	fmt.Println(lipgloss.JoinVertical(lipgloss.Left, label, panel))
+}
+
+// ShowReviewFindings displays the AI review findings grouped by severity
+func ShowReviewFindings(findings []ReviewFinding) {
+	if len(findings) == 0 {
+		fmt.Println(successStyle.Render("✅ Review found no issues"))
+		return
+	}
+
+	fmt.Println(headerStyle.Render(fmt.Sprintf("🔍 Review Findings (%d)", len(findings))))
+
+	var lines []string
+	for _, finding := range findings {
+		var severity string
+		switch finding.Severity {
+		case SeverityError:
+			severity = errorStyle.Render("error  ")
+		case SeverityWarning:
+			severity = warningStyle.Render("warning")
+		default:
+			severity = infoStyle.Render("info   ")
+		}
+		location := configHeaderStyle.Render(formatFindingLocation(finding))
+		lines = append(lines, fmt.Sprintf("%s %s\n        %s", severity, location, finding.Message))
+	}
+	fmt.Println(panelStyle.Render(strings.Join(lines, "\n")))
+}
+
+// maxSplitFilesShown is how many files of each proposed part are listed
+const maxSplitFilesShown = 8
+
+// ShowSplitPlan displays the proposed stacked PRs with their files and commits
+func ShowSplitPlan(plan *SplitPlan) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("✂️  Proposed split into %d stacked PRs", len(plan.Groups))))
+
+	var parts []string
+	for i, group := range plan.Groups {
+		added, removed := group.LinesChanged()
+		lines := []string{
+			configHeaderStyle.Render(fmt.Sprintf("%d. %s", i+1, group.Title)),
+			fmt.Sprintf("   %s: %d file(s), %s %s", group.Name, len(group.Files),
+				insertedStyle.Render(fmt.Sprintf("+%d", added)), deletedStyle.Render(fmt.Sprintf("-%d", removed))),
+		}
+		for j, file := range group.Files {
+			if j == maxSplitFilesShown {
+				lines = append(lines, fmt.Sprintf("     … and %d more", len(group.Files)-maxSplitFilesShown))
+				break
+			}
+			lines = append(lines, "     "+file.Path)
+		}
+		for _, commit := range group.Commits {
+			lines = append(lines, fmt.Sprintf("     %s %s", commit.SHA[:7], commit.Subject))
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	fmt.Println(panelStyle.Render(strings.Join(parts, "\n\n")))
+
+	if len(plan.MixedCommits) > 0 {
+		fmt.Println(warningStyle.Render(fmt.Sprintf("⚠️  %d commit(s) touch several parts, their changes are divided between the branches", len(plan.MixedCommits))))
+	}
+}
+
+// ShowSplitResult lists the stacked branches and draft PRs that were created
+func ShowSplitResult(plan *SplitPlan) {
+	fmt.Println()
+	fmt.Println(successStyle.Render(fmt.Sprintf("🎉 Created %d stacked draft PRs", len(plan.Groups))))
+	for i, group := range plan.Groups {
+		fmt.Printf("  %d. %s\n     %s\n", i+1, group.Branch, group.PRURL)
+	}
+}
+
 // SpinnerModel represents a Bubble Tea spinner
 type SpinnerModel struct {
 	spinner   spinner.Model
@@ -225,6 +500,16 @@ func (m SpinnerModel) View() string {
 
 // RunSpinnerWithTask runs a spinner while executing a task
 func RunSpinnerWithTask(message string, task func() error) error {
+	if nonInteractive {
+		err := task()
+		if err != nil {
+			fmt.Println(errorStyle.Render("❌ " + message + " - Failed!"))
+		} else {
+			fmt.Println(successStyle.Render("✅ " + message + " - Done!"))
+		}
+		return err
+	}
+
 	model := NewSpinner(message)
 
 	p := tea.NewProgram(model)
@@ -269,9 +554,9 @@ func ShowError(message string, err error) {
 }
 
 // ShowPRSuccess displays successful PR creation with prominent URL
-func ShowPRSuccess(prURL string) {
+func ShowPRSuccess(prURL string, opts PROptions) {
 	fmt.Println()
-	fmt.Println(successStyle.Render("🎉 Pull Request Created Successfully as Draft!"))
+	fmt.Println(successStyle.Render("🎉 Pull Request Created Successfully " + describePRState(opts) + "!"))
 
 	urlHeader := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#000000")). // Black text
@@ -289,6 +574,18 @@ func ShowPRSuccess(prURL string) {
 	fmt.Println(urlPanel)
 }
 
+// describePRState returns a human readable description of the PR state
+func describePRState(opts PROptions) string {
+	switch opts.State {
+	case PRStateReady:
+		return "as Ready for Review"
+	case PRStateAutoMerge:
+		return fmt.Sprintf("with Auto-Merge (%s)", opts.MergeMethod)
+	default:
+		return "as Draft"
+	}
+}
+
 // AskConfirmation prompts the user for confirmation
 func AskConfirmation(message string) bool {
 	fmt.Print(warningStyle.Render("❓ " + message + " (Y/n): "))
@@ -304,23 +601,123 @@ func AskConfirmation(message string) bool {
 	return strings.ToLower(response) != "n" && strings.ToLower(response) != "no"
 }
 
+// AskConfirmationDefaultNo prompts the user for confirmation of a risky action, defaulting to no
+func AskConfirmationDefaultNo(message string) bool {
+	fmt.Print(warningStyle.Render("❓ " + message + " (y/N): "))
+
+	var response string
+	fmt.Scanln(&response)
+
+	response = strings.ToLower(strings.TrimSpace(response))
+	return response == "y" || response == "yes"
+}
+
+// ShowWorkingTreeStatus summarises the uncommitted changes that the PR analysis excludes
+func ShowWorkingTreeStatus(status *WorkingTreeStatus) {
+	fmt.Println(warningStyle.Render("⚠️  You have uncommitted changes. They are not part of the PR analysis:"))
+
+	groups := []struct {
+		label string
+		files []string
+	}{
+		{"Staged", status.Staged},
+		{"Not staged", status.Unstaged},
+		{"Untracked", status.Untracked},
+	}
+
+	var lines []string
+	for _, group := range groups {
+		if len(group.files) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s (%d):", group.label, len(group.files)))
+		for _, file := range group.files {
+			lines = append(lines, "  "+file)
+		}
+	}
+	fmt.Println(panelStyle.Render(strings.Join(lines, "\n")))
+}
+
+// DirtyTreeAction represents the user's choice when the working tree has uncommitted changes
+type DirtyTreeAction int
+
+const (
+	DirtyContinue DirtyTreeAction = iota
+	DirtyCommit
+	DirtyIncludeStaged
+	DirtyAbort
+)
+
+// AskDirtyTreeAction prompts the user how to handle uncommitted changes
+func AskDirtyTreeAction(status *WorkingTreeStatus) DirtyTreeAction {
+	fmt.Println()
+	fmt.Println(headerStyle.Render("How should uncommitted changes be handled?"))
+	fmt.Println(infoStyle.Render("  [c] Commit tracked changes with a generated commit message"))
+	if len(status.Staged) > 0 {
+		fmt.Println(infoStyle.Render("  [s] Include staged changes in the analysis (without committing)"))
+	}
+	fmt.Println(infoStyle.Render("  [i] Ignore them and continue"))
+	fmt.Println(infoStyle.Render("  [a] Abort"))
+	fmt.Print(warningStyle.Render("❓ Your choice (c/s/i/a): "))
+
+	var response string
+	fmt.Scanln(&response)
+
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "c", "commit":
+		return DirtyCommit
+	case "s", "staged":
+		if len(status.Staged) > 0 {
+			return DirtyIncludeStaged
+		}
+	case "i", "ignore", "":
+		return DirtyContinue
+	case "a", "abort":
+		return DirtyAbort
+	}
+
+	fmt.Println(warningStyle.Render("⚠️  Invalid choice, aborting"))
+	return DirtyAbort
+}
+
 // RefinementChoice represents the user's choice after viewing generated PR content
 type RefinementChoice int
 
 const (
 	ChoiceAccept RefinementChoice = iota
 	ChoiceRefine
+	ChoiceTitles
+	ChoiceToggleState
+	ChoiceUndo
+	ChoiceRedo
+	ChoiceSelectVersion
 	ChoiceCancel
 )
 
-// AskRefinementOrAccept prompts the user to accept, refine, or cancel the PR
-func AskRefinementOrAccept() RefinementChoice {
+// AskRefinementOrAccept prompts the user to accept, refine, or cancel the PR.
+// Undo, redo and version selection are offered once there is more than one version.
+func AskRefinementOrAccept(opts PROptions, version, versions int) RefinementChoice {
 	fmt.Println()
 	fmt.Println(headerStyle.Render("What would you like to do?"))
-	fmt.Println(infoStyle.Render("  [a] Accept and create PR"))
+	fmt.Println(infoStyle.Render("  [a] Accept and create PR " + describePRState(opts)))
 	fmt.Println(infoStyle.Render("  [r] Refine with feedback"))
+	fmt.Println(infoStyle.Render("  [t] Pick from alternative titles"))
+	fmt.Println(infoStyle.Render(fmt.Sprintf("  [s] Switch PR state (next: %s)", opts.State.Next())))
+	keys := "a/r/t/s"
+	if version > 1 {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("  [u] Undo (back to version %d)", version-1)))
+		keys += "/u"
+	}
+	if version < versions {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("  [y] Redo (forward to version %d)", version+1)))
+		keys += "/y"
+	}
+	if versions > 1 {
+		fmt.Println(infoStyle.Render(fmt.Sprintf("  [v] Go to version (1-%d)", versions)))
+		keys += "/v"
+	}
 	fmt.Println(infoStyle.Render("  [c] Cancel"))
-	fmt.Print(warningStyle.Render("❓ Your choice (a/r/c): "))
+	fmt.Print(warningStyle.Render(fmt.Sprintf("❓ Your choice (%s/c): ", keys)))
 
 	var response string
 	fmt.Scanln(&response)
@@ -330,6 +727,16 @@ func AskRefinementOrAccept() RefinementChoice {
 		return ChoiceAccept
 	case "r", "refine":
 		return ChoiceRefine
+	case "t", "titles":
+		return ChoiceTitles
+	case "s", "state":
+		return ChoiceToggleState
+	case "u", "undo":
+		return ChoiceUndo
+	case "y", "redo":
+		return ChoiceRedo
+	case "v", "version":
+		return ChoiceSelectVersion
 	case "c", "cancel":
 		return ChoiceCancel
 	default:
@@ -338,6 +745,91 @@ func AskRefinementOrAccept() RefinementChoice {
 	}
 }
 
+// AskCandidate shows the candidates as a numbered list and returns the index picked, or -1 if none was.
+// With showBodies, each candidate is shown with its full body, otherwise only the titles are listed.
+func AskCandidate(message string, candidates []Candidate, showBodies bool) int {
+	fmt.Println()
+	if showBodies {
+		for i, candidate := range candidates {
+			fmt.Println(headerStyle.Render(fmt.Sprintf("Option %d (%s)", i+1, candidate.Label)))
+			ShowGeneratedContent(candidate.Title, candidate.Body)
+		}
+	} else {
+		var lines []string
+		for i, candidate := range candidates {
+			lines = append(lines, fmt.Sprintf("[%d] %s", i+1, candidate.Title))
+		}
+		fmt.Println(panelStyle.Render(strings.Join(lines, "\n")))
+	}
+
+	hint := "Enter to keep current"
+	if showBodies {
+		hint = "Enter for option 1"
+	}
+	fmt.Print(warningStyle.Render(fmt.Sprintf("❓ %s (1-%d, %s): ", message, len(candidates), hint)))
+
+	var response string
+	fmt.Scanln(&response)
+
+	index, err := strconv.Atoi(strings.TrimSpace(response))
+	if err != nil || index < 1 || index > len(candidates) {
+		return -1
+	}
+	return index - 1
+}
+
+// AskVersion prompts for the version to make the current candidate, returning 0 if none was given
+func AskVersion(versions int) int {
+	fmt.Print(warningStyle.Render(fmt.Sprintf("❓ Version (1-%d): ", versions)))
+
+	var response string
+	fmt.Scanln(&response)
+
+	version, err := strconv.Atoi(strings.TrimSpace(response))
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// CommitChoice represents the user's choice after viewing a generated commit message
+type CommitChoice int
+
+const (
+	CommitChoiceAccept CommitChoice = iota
+	CommitChoiceRefine
+	CommitChoiceEdit
+	CommitChoiceCancel
+)
+
+// AskCommitAction prompts the user to accept, refine, edit, or cancel the commit message
+func AskCommitAction() CommitChoice {
+	fmt.Println()
+	fmt.Println(headerStyle.Render("What would you like to do?"))
+	fmt.Println(infoStyle.Render("  [a] Accept and commit"))
+	fmt.Println(infoStyle.Render("  [r] Refine with feedback"))
+	fmt.Println(infoStyle.Render("  [e] Edit in " + GetEditor()))
+	fmt.Println(infoStyle.Render("  [c] Cancel"))
+	fmt.Print(warningStyle.Render("❓ Your choice (a/r/e/c): "))
+
+	var response string
+	fmt.Scanln(&response)
+
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "a", "accept", "":
+		return CommitChoiceAccept
+	case "r", "refine":
+		return CommitChoiceRefine
+	case "e", "edit":
+		return CommitChoiceEdit
+	case "c", "cancel":
+		return CommitChoiceCancel
+	default:
+		fmt.Println(warningStyle.Render("⚠️  Invalid choice, please try again"))
+		return AskCommitAction()
+	}
+}
+
 // AskRefinementFeedback prompts the user to provide feedback for refining the PR
 func AskRefinementFeedback() string {
 	fmt.Println()
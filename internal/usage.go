@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UsageRecord is the token usage and cost of a single provider call
+type UsageRecord struct {
+	Time                time.Time `json:"time"`
+	RepoRoot            string    `json:"repo_root,omitempty"`
+	Branch              string    `json:"branch,omitempty"`
+	Operation           string    `json:"operation"` // e.g. "pr", "pr-refine", "commit", "review"
+	Provider            string    `json:"provider"`
+	InputTokens         int       `json:"input_tokens"`
+	OutputTokens        int       `json:"output_tokens"`
+	CacheCreationTokens int       `json:"cache_creation_tokens,omitempty"`
+	CacheReadTokens     int       `json:"cache_read_tokens,omitempty"`
+	CostUSD             float64   `json:"cost_usd"`
+}
+
+// UsageTotals sums the usage of several provider calls
+type UsageTotals struct {
+	Calls        int
+	InputTokens  int // Including prompt cache reads and writes
+	OutputTokens int
+	CostUSD      float64
+}
+
+// Add includes a record in the totals
+func (t *UsageTotals) Add(record UsageRecord) {
+	t.Calls++
+	t.InputTokens += record.InputTokens + record.CacheCreationTokens + record.CacheReadTokens
+	t.OutputTokens += record.OutputTokens
+	t.CostUSD += record.CostUSD
+}
+
+// UsageGroup is the totals for one day, repository or other key
+type UsageGroup struct {
+	Key    string
+	Label  string // Shown instead of Key, e.g. a repository's directory name
+	Totals UsageTotals
+}
+
+var (
+	// usageMu guards runUsage and appends to the usage log, since variants are generated in parallel
+	usageMu  sync.Mutex
+	runUsage UsageTotals
+)
+
+// RecordUsage adds a provider call to this run's totals and appends it to the usage log.
+// Logging failures are ignored so they never break generation.
+func RecordUsage(config *Config, record UsageRecord) {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if record.RepoRoot == "" {
+		record.RepoRoot, _ = GetRepoRoot()
+	}
+	if record.Branch == "" {
+		record.Branch, _ = GetCurrentBranch()
+	}
+
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	runUsage.Add(record)
+
+	if config == nil || config.ConfigDir == "" {
+		return
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	file, err := os.OpenFile(usageLogPath(config), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, _ = file.Write(append(data, '\n'))
+}
+
+// RunUsage returns the totals of the provider calls made by this process
+func RunUsage() UsageTotals {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	return runUsage
+}
+
+// usageLogPath returns the file the usage log is appended to
+func usageLogPath(config *Config) string {
+	return filepath.Join(config.ConfigDir, "usage.jsonl")
+}
+
+// LoadUsageLog reads the usage records made since the given time
+func LoadUsageLog(config *Config, since time.Time) ([]UsageRecord, error) {
+	file, err := os.Open(usageLogPath(config))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer file.Close()
+
+	var records []UsageRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// Skip damaged lines rather than losing the whole log
+			continue
+		}
+		if record.Time.Before(since) {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage log: %w", err)
+	}
+	return records, nil
+}
+
+// SummarizeUsage groups records by the key returned for each, sorted by key
+func SummarizeUsage(records []UsageRecord, key func(UsageRecord) string) []UsageGroup {
+	totals := make(map[string]*UsageTotals)
+	for _, record := range records {
+		k := key(record)
+		if totals[k] == nil {
+			totals[k] = &UsageTotals{}
+		}
+		totals[k].Add(record)
+	}
+
+	groups := make([]UsageGroup, 0, len(totals))
+	for k, t := range totals {
+		groups = append(groups, UsageGroup{Key: k, Label: k, Totals: *t})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+// UsageDay groups usage by local calendar day
+func UsageDay(record UsageRecord) string {
+	return record.Time.Local().Format("2006-01-02")
+}
+
+// UsageRepo groups usage by repository root, so checkouts with the same directory name stay apart
+func UsageRepo(record UsageRecord) string {
+	return record.RepoRoot
+}
+
+// LabelUsageRepos labels repository groups with their directory name, or with the full
+// root when another repository has the same directory name
+func LabelUsageRepos(groups []UsageGroup) {
+	counts := make(map[string]int)
+	for _, group := range groups {
+		counts[filepath.Base(group.Key)]++
+	}
+	for i, group := range groups {
+		switch name := filepath.Base(group.Key); {
+		case group.Key == "":
+			groups[i].Label = "(none)"
+		case counts[name] > 1:
+			groups[i].Label = group.Key
+		default:
+			groups[i].Label = name
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"unicode"
+)
+
+// maxWordDiffCells bounds the size of the LCS table; larger inputs are shown as a full replacement
+const maxWordDiffCells = 4_000_000
+
+// DiffOpKind says whether a piece of text was kept, inserted or deleted
+type DiffOpKind int
+
+const (
+	DiffEqual DiffOpKind = iota
+	DiffInsert
+	DiffDelete
+)
+
+// DiffOp is a run of text with the same kind in a word-level diff
+type DiffOp struct {
+	Kind DiffOpKind
+	Text string
+}
+
+// WordDiff returns the word-level changes that turn oldText into newText.
+// Whitespace is kept as separate tokens so the result can be printed as is.
+func WordDiff(oldText, newText string) []DiffOp {
+	a, b := tokenizeWords(oldText), tokenizeWords(newText)
+
+	if len(a)*len(b) > maxWordDiffCells {
+		return mergeDiffOps([]DiffOp{{DiffDelete, oldText}, {DiffInsert, newText}})
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []DiffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, DiffOp{DiffEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, DiffOp{DiffDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, DiffOp{DiffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, DiffOp{DiffDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, DiffOp{DiffInsert, b[j]})
+	}
+
+	return mergeDiffOps(ops)
+}
+
+// HasChanges reports whether the diff contains any insertion or deletion
+func HasChanges(ops []DiffOp) bool {
+	for _, op := range ops {
+		if op.Kind != DiffEqual {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenizeWords splits text into alternating runs of whitespace and non-whitespace
+func tokenizeWords(text string) []string {
+	var tokens []string
+	start := 0
+	prevSpace := false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if i > 0 && space != prevSpace {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		prevSpace = space
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// mergeDiffOps joins adjacent operations of the same kind and drops empty ones
+func mergeDiffOps(ops []DiffOp) []DiffOp {
+	var merged []DiffOp
+	for _, op := range ops {
+		if op.Text == "" {
+			continue
+		}
+		if n := len(merged); n > 0 && merged[n-1].Kind == op.Kind {
+			merged[n-1].Text += op.Text
+			continue
+		}
+		merged = append(merged, op)
+	}
+	return merged
+}